@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"k8s.io/client-go/kubernetes"
+)
+
+// getCurrentUser returns the user recorded as the one downscaling resources, or "unknown" if the API server can't tell
+func getCurrentUser(ctx context.Context, clientset kubernetes.Interface) string {
+	user, err := pkg.GetCurrentUser(ctx, clientset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not find out the current user: %v\n", err)
+		return "unknown"
+	}
+	return user
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // schedules may use any time zone, even where the system has no time zone database
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/utils/clock"
+)
+
+var (
+	controllerInterval time.Duration
+	leaderElect        bool
+	leaseNamespace     string
+	leaseName          string
+)
+
+var controllerCmd = &cobra.Command{
+	Use:   "controller",
+	Short: "Downscale and upscale namespaces on the schedules set in their annotations",
+	Long: `Run until interrupted, downscaling and upscaling namespaces on the schedules set in their annotations.
+
+szero/sleep-schedule lists the time ranges a namespace sleeps through, e.g. "Mon-Fri 20:00-07:00; Sat-Sun".
+Alternatively, szero/sleep-cron and szero/wake-cron hold cron expressions, e.g. "0 20 * * 1-5" and "0 7 * * 1-5".
+szero/schedule-timezone sets the time zone of the schedule, UTC by default.
+
+The --skip-* flags, --scale-subresources, --selector and --field-selector select the resources scaled in each
+namespace, as they do for down and up.`,
+	Example: "szero controller\nszero controller --namespace-selector env=preview --interval 30s",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		dynamicClient, err := pkg.GetDynamicClient(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		scaleClient, scalableResources := getScalableResourcesOrFatal(clientset)
+
+		controller := &pkg.Controller{
+			Clientset:         clientset,
+			DynamicClient:     dynamicClient,
+			Clock:             clock.RealClock{},
+			Logger:            logger,
+			Interval:          controllerInterval,
+			NamespaceSelector: namespaceSelector,
+			Features:          selectedFeatures(scalableResources),
+			ListOptions:       listOptions(),
+			ScaleClient:       scaleClient,
+			DryRun:            dryRun,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if !leaderElect {
+			controller.Run(ctx)
+			return
+		}
+
+		hostname, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		controller.RunWithLeaderElection(ctx, leaseNamespace, leaseName, hostname+"_"+string(uuid.NewUUID()))
+		if ctx.Err() == nil {
+			logger.Error("Lost the lease, exiting")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	controllerCmd.Flags().DurationVar(&controllerInterval, "interval", time.Minute, "How often to check the schedules")
+	controllerCmd.Flags().BoolVar(&leaderElect, "leader-elect", true, "Use a Lease so that only one replica of the controller acts at a time")
+	controllerCmd.Flags().StringVar(&leaseNamespace, "lease-namespace", pkg.InClusterNamespace(), "Namespace of the Lease used for leader election, the one of the pod the controller runs in by default")
+	controllerCmd.Flags().StringVar(&leaseName, "lease-name", "szero-controller", "Name of the Lease used for leader election")
+	rootCmd.AddCommand(controllerCmd)
+}
@@ -4,50 +4,130 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/jadolg/szero/pkg"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	snapshotPath string
+	reason       string
+	downFor      time.Duration
+	wakeUpImage  string
 )
 
 var downCmd = &cobra.Command{
 	Use:     "down",
-	Short:   "Downscale all deployments/statefulsets/daemonsets in the desired namespaces",
+	Short:   "Downscale all deployments/statefulsets/daemonsets and suspend cronjobs/jobs in the desired namespaces",
 	Example: "szero down -n default -n klum",
 	Aliases: []string{"downscale"},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		printer, err := pkg.NewPrinter(output, "down", dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if downFor > 0 && wakeUpImage == "" {
+			fmt.Fprintln(os.Stderr, "Error: --image is required to wake the namespaces up with --for")
+			os.Exit(1)
+		}
+
+		// Waiting for the pods to be gone is waiting as well
+		if waitForPods {
+			wait = true
+		}
+
 		if dryRun {
 			fmt.Fprintln(os.Stderr, "⚠️  Running in dry-run mode, no changes will be made")
 		}
 
-		clientset, err := pkg.GetClientset(kubeconfig, kubecontext)
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		printer := pkg.NewTreePrinter()
+		dynamicClient, err := pkg.GetDynamicClient(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		scaleClient, scalableResources := getScalableResourcesOrFatal(clientset)
+
 		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+		preflightOrFatal(ctx, clientset, selectedFeatures(scalableResources))
+
+		audit := pkg.Audit{
+			At:     time.Now().UTC(),
+			By:     getCurrentUser(ctx, clientset),
+			Reason: reason,
+		}
+
+		// Round the deadline up to the next minute, the resolution of the wake-up schedule
+		wakeUpAt := audit.At.Add(downFor).Truncate(time.Minute).Add(time.Minute)
+
+		// The Role of the wake-up job only grants the kinds the cluster serves
+		var wakeUpFeatures pkg.Features
+		if downFor > 0 {
+			wakeUpFeatures = servedFeaturesOrWarn(clientset, inClusterFeatures(scalableResources))
+		}
+
+		snapshot := pkg.NewSnapshot()
+		failed := false
 		for _, namespace := range namespaces {
 			result := pkg.NamespaceResult{
 				Namespace: namespace,
 			}
 
-			// Deployments
-			if skipDeployments {
-				result.Deployments = pkg.ResourceGroup{Type: "Deployments", Skipped: true}
+			if isNamespaceExcludedOrFatal(ctx, clientset, namespace) {
+				result.Excluded = true
+				if err := printer.PrintNamespaceResult(result); err != nil {
+					fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
+					os.Exit(1)
+				}
+				continue
+			}
+
+			result.Audit = &audit
+
+			deployments, statefulsets, listed := getWorkloads(ctx, clientset, namespace, &result)
+			failed = failed || !listed
+
+			// KEDA ScaledObjects, whose targets are left for KEDA to scale
+			scaledObjects := &unstructured.UnstructuredList{}
+			if skipKeda {
+				result.ScaledObjects = pkg.ResourceGroup{Type: "ScaledObjects", Skipped: true}
+			} else if allScaledObjects, err := pkg.GetScaledObjects(ctx, dynamicClient, namespace); err != nil {
+				result.ScaledObjects = listFailed("ScaledObjects", "scaledobjects", err)
+				failed = true
 			} else {
-				deployments, err := pkg.GetDeployments(ctx, clientset, namespace)
+				scaledObjects = pkg.ScaledObjectsFor(allScaledObjects, deployments, statefulsets)
+				scaledObjectInfos, err := pkg.DownscaleScaledObjects(ctx, dynamicClient, scaledObjects, dryRun)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting deployments: %v\n", err)
-					os.Exit(1)
+					fmt.Fprintf(os.Stderr, "Error pausing scaledobjects: %v\n", err)
+					failed = true
 				}
-				deploymentInfos, err := pkg.DownscaleDeployments(ctx, clientset, deployments, dryRun)
+				result.ScaledObjects = pkg.ResourceGroup{
+					Type:      "ScaledObjects",
+					Resources: scaledObjectInfos,
+				}
+			}
+
+			// Deployments
+			if skipDeployments {
+				result.Deployments = pkg.ResourceGroup{Type: "Deployments", Skipped: true}
+			} else if result.Deployments.Error == "" {
+				deploymentInfos, err := pkg.DownscaleDeployments(ctx, clientset, pkg.DeploymentsWithoutScaledObjects(deployments, scaledObjects), dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error downscaling deployments: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.Deployments = pkg.ResourceGroup{
 					Type:      "Deployments",
@@ -58,16 +138,11 @@ var downCmd = &cobra.Command{
 			// StatefulSets
 			if skipStatefulsets {
 				result.StatefulSets = pkg.ResourceGroup{Type: "StatefulSets", Skipped: true}
-			} else {
-				statefulsets, err := pkg.GetStatefulSets(ctx, clientset, namespace)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting statefulsets: %v\n", err)
-					os.Exit(1)
-				}
-				statefulsetInfos, err := pkg.DownscaleStatefulSets(ctx, clientset, statefulsets, dryRun)
+			} else if result.StatefulSets.Error == "" {
+				statefulsetInfos, err := pkg.DownscaleStatefulSets(ctx, clientset, pkg.StatefulSetsWithoutScaledObjects(statefulsets, scaledObjects), dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error downscaling statefulsets: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.StatefulSets = pkg.ResourceGroup{
 					Type:      "StatefulSets",
@@ -78,16 +153,14 @@ var downCmd = &cobra.Command{
 			// DaemonSets
 			if skipDaemonsets {
 				result.DaemonSets = pkg.ResourceGroup{Type: "DaemonSets", Skipped: true}
+			} else if daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace, listOptions()); err != nil {
+				result.DaemonSets = listFailed("DaemonSets", "daemonsets", err)
+				failed = true
 			} else {
-				daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting daemonsets: %v\n", err)
-					os.Exit(1)
-				}
 				daemonsetInfos, err := pkg.DownscaleDaemonsets(ctx, clientset, daemonsets, dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error downscaling daemonsets: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.DaemonSets = pkg.ResourceGroup{
 					Type:      "DaemonSets",
@@ -95,12 +168,142 @@ var downCmd = &cobra.Command{
 				}
 			}
 
+			// CronJobs
+			if skipCronJobs {
+				result.CronJobs = pkg.ResourceGroup{Type: "CronJobs", Skipped: true}
+			} else if cronjobs, err := pkg.GetCronJobs(ctx, clientset, namespace, listOptions()); err != nil {
+				result.CronJobs = listFailed("CronJobs", "cronjobs", err)
+				failed = true
+			} else {
+				cronjobInfos, err := pkg.DownscaleCronJobs(ctx, clientset, cronjobs, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error suspending cronjobs: %v\n", err)
+					failed = true
+				}
+				result.CronJobs = pkg.ResourceGroup{
+					Type:      "CronJobs",
+					Resources: cronjobInfos,
+				}
+			}
+
+			// Jobs
+			if skipJobs {
+				result.Jobs = pkg.ResourceGroup{Type: "Jobs", Skipped: true}
+			} else if jobs, err := pkg.GetJobs(ctx, clientset, namespace, listOptions()); err != nil {
+				result.Jobs = listFailed("Jobs", "jobs", err)
+				failed = true
+			} else {
+				jobInfos, err := pkg.DownscaleJobs(ctx, clientset, jobs, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error suspending jobs: %v\n", err)
+					failed = true
+				}
+				result.Jobs = pkg.ResourceGroup{
+					Type:      "Jobs",
+					Resources: jobInfos,
+				}
+			}
+
+			// HorizontalPodAutoscalers targeting the deployments and statefulsets above
+			if skipHPAs {
+				result.HPAs = pkg.ResourceGroup{Type: "HorizontalPodAutoscalers", Skipped: true}
+			} else if hpas, err := pkg.GetHorizontalPodAutoscalers(ctx, clientset, namespace); err != nil {
+				result.HPAs = listFailed("HorizontalPodAutoscalers", "horizontal pod autoscalers", err)
+				failed = true
+			} else {
+				hpaInfos, err := pkg.DownscaleHorizontalPodAutoscalers(ctx, clientset, pkg.HorizontalPodAutoscalersFor(hpas, result), dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error parking horizontal pod autoscalers: %v\n", err)
+					failed = true
+				}
+				result.HPAs = pkg.ResourceGroup{
+					Type:      "HorizontalPodAutoscalers",
+					Resources: hpaInfos,
+				}
+			}
+
+			// Resources exposing the scale subresource
+			for _, resource := range scalableResources {
+				objects, err := pkg.GetScalableObjects(ctx, dynamicClient, namespace, resource, listOptions())
+				if err != nil {
+					result.Scalables = append(result.Scalables, listFailed(resource.Name(), resource.Name(), err))
+					failed = true
+					continue
+				}
+				if len(objects.Items) == 0 {
+					continue
+				}
+				infos, err := pkg.DownscaleScalableObjects(ctx, dynamicClient, scaleClient, resource, objects, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error downscaling %s: %v\n", resource.Name(), err)
+					failed = true
+				}
+				result.Scalables = append(result.Scalables, pkg.ResourceGroup{
+					Type:      resource.Name(),
+					Resources: infos,
+				})
+			}
+
+			if err := pkg.RecordAudit(ctx, dynamicClient, result, scalableResources, audit, dryRun); err != nil {
+				fmt.Fprintf(os.Stderr, "Error recording who downscaled the resources: %v\n", err)
+				failed = true
+			}
+
+			if (snapshotPath != "" || !skipLedger) && !dryRun {
+				namespaceSnapshot, err := pkg.TakeNamespaceSnapshot(ctx, dynamicClient, namespace, scalableResources)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error taking snapshot of namespace %s: %v\n", namespace, err)
+					failed = true
+				} else {
+					snapshot.Namespaces = append(snapshot.Namespaces, namespaceSnapshot)
+				}
+				if err == nil && !skipLedger && len(namespaceSnapshot.Resources) > 0 {
+					if err := pkg.RecordLedger(ctx, clientset, namespaceSnapshot, audit.By); err != nil {
+						fmt.Fprintf(os.Stderr, "Error recording ledger in namespace %s: %v\n", namespace, err)
+						failed = true
+					}
+				}
+			}
+
+			if downFor > 0 {
+				wakeUp := pkg.WakeUp{
+					Namespace: namespace,
+					At:        wakeUpAt,
+					Image:     wakeUpImage,
+					Args:      inClusterArgs("wake-up", namespace),
+					Features:  wakeUpFeatures,
+				}
+				if err := pkg.CreateWakeUp(ctx, clientset, wakeUp, dryRun); err != nil {
+					fmt.Fprintf(os.Stderr, "Error scheduling the wake-up of namespace %s: %v\n", namespace, err)
+					failed = true
+				} else {
+					fmt.Fprintf(os.Stderr, "⏰ Namespace %s will be brought back up at %s\n", namespace, wakeUpAt.Format(time.RFC3339))
+				}
+			}
+
 			if err := printer.PrintNamespaceResult(result); err != nil {
 				fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
 				os.Exit(1)
 			}
 		}
 
+		if snapshotPath != "" {
+			if dryRun {
+				fmt.Fprintln(os.Stderr, "⚠️  Not writing a snapshot in dry-run mode")
+			} else if err := pkg.WriteSnapshotFile(snapshotPath, snapshot); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+			}
+		}
+
+		if err := printer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
+			os.Exit(1)
+		}
+		if failed {
+			os.Exit(1)
+		}
+
 		if wait && !dryRun {
 			waitForResourcesOrFatal(ctx, clientset, true)
 		}
@@ -108,5 +311,9 @@ var downCmd = &cobra.Command{
 }
 
 func init() {
+	downCmd.Flags().StringVar(&snapshotPath, "snapshot", "", "Write the state needed to bring the namespaces back up to this file")
+	downCmd.Flags().StringVar(&reason, "reason", "", "Why the namespaces are downscaled, recorded on the downscaled resources")
+	downCmd.Flags().DurationVar(&downFor, "for", 0, "Bring the namespaces back up automatically after this long (e.g. 48h), using a CronJob running szero in each namespace")
+	downCmd.Flags().StringVar(&wakeUpImage, "image", "", "Container image with szero as entrypoint, used by the CronJob waking the namespaces up")
 	rootCmd.AddCommand(downCmd)
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var (
+	rbacName           string
+	rbacServiceAccount string
+)
+
+var rbacCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Print the minimal Role and RoleBinding a ServiceAccount needs to run szero with the same flags",
+	Long: "Print the minimal Role and RoleBinding a ServiceAccount needs to run szero with the same flags, in each of the namespaces. " +
+		"HPAs and KEDA ScaledObjects are left out when the cluster does not serve them. " +
+		"Namespace patterns, selectors and all namespaces get a ClusterRole and ClusterRoleBinding instead.",
+	Example: "szero rbac -n staging --service-account ops:szero > rbac.yaml\nszero rbac -A --skip-keda --service-account ops:szero",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		namespace, name, found := strings.Cut(rbacServiceAccount, ":")
+		if !found || namespace == "" || name == "" {
+			fmt.Fprintf(os.Stderr, "Error: invalid service account %q, expected <namespace>:<name>\n", rbacServiceAccount)
+			os.Exit(1)
+		}
+		subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}
+
+		// The resources exposing the scale subresource and the optional kinds served can only be discovered in the
+		// cluster, without one the rules of every optional kind are printed
+		features := selectedFeatures(nil)
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil && scaleSubresources {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not connect to the cluster, printing the rules of HPAs and KEDA ScaledObjects whether they are served or not: %v\n", err)
+		} else {
+			_, scalableResources := getScalableResourcesOrFatal(clientset)
+			features = servedFeaturesOrWarn(clientset, selectedFeatures(scalableResources))
+		}
+
+		rules := pkg.RequiredRules(features)
+		objects, err := pkg.RBACObjects(rbacName, subject, namespaceSelection(cmd), rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := pkg.WriteManifests(os.Stdout, objects); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing manifests: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rbacCmd.Flags().StringVar(&rbacName, "name", "szero", "Name of the roles and bindings")
+	rbacCmd.Flags().StringVar(&rbacServiceAccount, "service-account", "", "ServiceAccount to grant the permissions to, as <namespace>:<name>")
+	if err := rbacCmd.MarkFlagRequired("service-account"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	rootCmd.AddCommand(rbacCmd)
+}
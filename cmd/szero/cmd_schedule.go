@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	scheduleDown     string
+	scheduleUp       string
+	scheduleImage    string
+	scheduleTimeZone string
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage CronJobs bringing namespaces down and back up on a schedule from inside the cluster",
+}
+
+var scheduleCreateCmd = &cobra.Command{
+	Use:     "create",
+	Short:   "Create the CronJobs, ServiceAccount, Role and RoleBinding running szero on a schedule in the desired namespaces",
+	Example: "szero schedule create --down \"0 20 * * 1-5\" --up \"0 7 * * 1-5\" --image <registry>/szero:<version> -n staging\nszero schedule create --down \"0 20 * * 1-5\" --up \"0 7 * * 1-5\" --image <registry>/szero:<version> -n staging --dry-run > schedule.yaml",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// The manifests of namespaces named literally are rendered without a cluster, e.g. to commit them to a GitOps
+		// repository, with the permissions of every optional kind
+		var clientset kubernetes.Interface
+		features := inClusterFeatures(nil)
+		if !dryRun || scaleSubresources || !namespaceSelection(cmd).Literal() {
+			var err error
+			clientset, err = pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			_, scalableResources := getScalableResourcesOrFatal(clientset)
+			features = servedFeaturesOrWarn(clientset, inClusterFeatures(scalableResources))
+			resolveNamespacesOrFatal(ctx, cmd, clientset)
+		}
+
+		if dryRun {
+			var objects []runtime.Object
+			for _, namespace := range namespaces {
+				namespaceObjects, err := pkg.ScheduleObjects(sleepSchedule(namespace, features))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				objects = append(objects, namespaceObjects...)
+			}
+			if err := pkg.WriteManifests(os.Stdout, objects); err != nil {
+				fmt.Fprintf(os.Stderr, "Error printing manifests: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		failed := false
+		for _, namespace := range namespaces {
+			if err := pkg.ApplySchedule(ctx, clientset, sleepSchedule(namespace, features)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error scheduling namespace %s: %v\n", namespace, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("📅 Namespace %s goes down at \"%s\" and up at \"%s\" (%s)\n", namespace, scheduleDown, scheduleUp, scheduleTimeZone)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the schedules of the desired namespaces",
+	Example: "szero schedule list -A",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if output != "tree" && output != "json" && output != "yaml" {
+			fmt.Fprintf(os.Stderr, "Error: unknown output format %q, expected one of tree, json, yaml\n", output)
+			os.Exit(1)
+		}
+
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+
+		schedules := []pkg.SleepSchedule{}
+		for _, namespace := range namespaces {
+			schedule, err := pkg.GetSchedule(ctx, clientset, namespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting schedule of namespace %s: %v\n", namespace, err)
+				os.Exit(1)
+			}
+			if schedule != nil {
+				schedules = append(schedules, *schedule)
+			}
+		}
+
+		if output != "tree" {
+			if err := pkg.WriteStructured(os.Stdout, output, schedules); err != nil {
+				fmt.Fprintf(os.Stderr, "Error printing schedules: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(schedules) == 0 {
+			fmt.Println("No namespace is scheduled by szero in the selected namespaces")
+			return
+		}
+		printer := pkg.NewTreePrinter()
+		for _, schedule := range schedules {
+			if err := printer.PrintSchedule(schedule); err != nil {
+				fmt.Fprintf(os.Stderr, "Error printing schedules: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+var scheduleDeleteCmd = &cobra.Command{
+	Use:     "delete",
+	Short:   "Delete the schedules of the desired namespaces and the permissions they ran with",
+	Example: "szero schedule delete -n staging",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if dryRun {
+			fmt.Fprintln(os.Stderr, "⚠️  Running in dry-run mode, no changes will be made")
+		}
+
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+
+		failed := false
+		for _, namespace := range namespaces {
+			deleted, err := pkg.DeleteSchedule(ctx, clientset, namespace, dryRun)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error deleting schedule of namespace %s: %v\n", namespace, err)
+				failed = true
+				continue
+			}
+			if deleted {
+				fmt.Printf("🗑️  Deleted the schedule of namespace %s\n", namespace)
+			} else {
+				fmt.Printf("Namespace %s has no schedule\n", namespace)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// sleepSchedule describes the schedule of a namespace according to the flags
+func sleepSchedule(namespace string, features pkg.Features) pkg.SleepSchedule {
+	return pkg.SleepSchedule{
+		Namespace: namespace,
+		Down:      scheduleDown,
+		Up:        scheduleUp,
+		TimeZone:  scheduleTimeZone,
+		Image:     scheduleImage,
+		DownArgs:  inClusterArgs("down", namespace),
+		UpArgs:    inClusterArgs("up", namespace),
+		Features:  features,
+	}
+}
+
+func init() {
+	scheduleCreateCmd.Flags().StringVar(&scheduleDown, "down", "", "Cron expression for when to bring the namespaces down (e.g. \"0 20 * * 1-5\")")
+	scheduleCreateCmd.Flags().StringVar(&scheduleUp, "up", "", "Cron expression for when to bring the namespaces back up (e.g. \"0 7 * * 1-5\")")
+	scheduleCreateCmd.Flags().StringVar(&scheduleImage, "image", "", "Container image with szero as entrypoint, run by the CronJobs")
+	scheduleCreateCmd.Flags().StringVar(&scheduleTimeZone, "time-zone", "Etc/UTC", "Time zone of the cron expressions")
+	for _, flag := range []string{"down", "up", "image"} {
+		if err := scheduleCreateCmd.MarkFlagRequired(flag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	scheduleCmd.AddCommand(scheduleCreateCmd, scheduleListCmd, scheduleDeleteCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
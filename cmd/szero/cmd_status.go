@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show the workloads currently downscaled by szero and what up will restore",
+	Example: "szero status -n default\nszero status -A -o json",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if output != "tree" && output != "json" && output != "yaml" {
+			fmt.Fprintf(os.Stderr, "Error: unknown output format %q, expected one of tree, json, yaml\n", output)
+			os.Exit(1)
+		}
+
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		dynamicClient, err := pkg.GetDynamicClient(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		_, scalableResources := getScalableResourcesOrFatal(clientset)
+
+		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+
+		report := pkg.StatusReport{SchemaVersion: pkg.SchemaVersion, Namespaces: []pkg.NamespaceStatus{}}
+		for _, namespace := range namespaces {
+			status, err := pkg.GetNamespaceStatus(ctx, clientset, dynamicClient, namespace, scalableResources, listOptions())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting status of namespace %s: %v\n", namespace, err)
+				os.Exit(1)
+			}
+			if len(status.Workloads) > 0 || status.WakeUpAt != nil {
+				report.Namespaces = append(report.Namespaces, status)
+			}
+		}
+
+		if output != "tree" {
+			if err := pkg.WriteStructured(os.Stdout, output, report); err != nil {
+				fmt.Fprintf(os.Stderr, "Error printing status: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(report.Namespaces) == 0 {
+			fmt.Println("Nothing is downscaled by szero in the selected namespaces")
+			return
+		}
+		printer := pkg.NewTreePrinter()
+		for _, status := range report.Namespaces {
+			if err := printer.PrintNamespaceStatus(status); err != nil {
+				fmt.Fprintf(os.Stderr, "Error printing status: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
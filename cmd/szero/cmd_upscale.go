@@ -7,47 +7,123 @@ import (
 
 	"github.com/jadolg/szero/pkg"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
 )
 
+var fromSnapshot string
+
 var upCmd = &cobra.Command{
 	Use:     "up",
-	Short:   "Upscale all deployments/statefulsets/daemonsets in the desired namespaces to their original size",
+	Short:   "Upscale all deployments/statefulsets/daemonsets and resume cronjobs/jobs in the desired namespaces to their original state",
 	Example: "szero up -n default -n klum",
 	Aliases: []string{"upscale"},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		printer, err := pkg.NewPrinter(output, "up", dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		if dryRun {
 			fmt.Fprintln(os.Stderr, "⚠️  Running in dry-run mode, no changes will be made")
 		}
 
-		clientset, err := pkg.GetClientset(kubeconfig, kubecontext)
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		dynamicClient, err := pkg.GetDynamicClient(kubeconfig, kubecontext, authOverrides())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		scaleClient, scalableResources := getScalableResourcesOrFatal(clientset)
 
-		printer := pkg.NewTreePrinter()
 		ctx := context.Background()
+		snapshot := readSnapshotOrFatal(cmd)
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+		preflightOrFatal(ctx, clientset, selectedFeatures(scalableResources))
+
+		failed := false
+		var upscaled []string
 		for _, namespace := range namespaces {
 			result := pkg.NamespaceResult{
 				Namespace: namespace,
 			}
 
-			// Deployments
-			if skipDeployments {
-				result.Deployments = pkg.ResourceGroup{Type: "Deployments", Skipped: true}
+			if isNamespaceExcludedOrFatal(ctx, clientset, namespace) {
+				result.Excluded = true
+				if err := printer.PrintNamespaceResult(result); err != nil {
+					fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
+					os.Exit(1)
+				}
+				continue
+			}
+
+			upscaled = append(upscaled, namespace)
+
+			if namespaceSnapshot, found := snapshot.Namespace(namespace); found {
+				restored, err := pkg.RestoreNamespaceSnapshot(ctx, dynamicClient, namespaceSnapshot, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error restoring snapshot: %v\n", err)
+					failed = true
+				}
+				if len(restored) > 0 {
+					fmt.Fprintf(os.Stderr, "♻️  Restored the state of %d resources in namespace %s from the snapshot\n", len(restored), namespace)
+				}
+			}
+
+			// Annotations still on the resources are preferred, the ledger only fills in the missing ones
+			// of the resources selected now
+			ledger := selectedLedgerOrWarn(ctx, clientset, dynamicClient, namespace, scalableResources)
+			if ledger != nil {
+				restored, err := pkg.RestoreNamespaceSnapshot(ctx, dynamicClient, ledger.Snapshot(namespace), dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error restoring ledger: %v\n", err)
+					failed = true
+				}
+				if len(restored) > 0 {
+					fmt.Fprintf(os.Stderr, "♻️  Restored the state of %d resources in namespace %s from the ledger\n", len(restored), namespace)
+				}
+			}
+
+			deployments, statefulsets, listed := getWorkloads(ctx, clientset, namespace, &result)
+			failed = failed || !listed
+
+			// KEDA ScaledObjects, whose targets are left for KEDA to scale
+			scaledObjects := &unstructured.UnstructuredList{}
+			if skipKeda {
+				result.ScaledObjects = pkg.ResourceGroup{Type: "ScaledObjects", Skipped: true}
+			} else if allScaledObjects, err := pkg.GetScaledObjects(ctx, dynamicClient, namespace); err != nil {
+				result.ScaledObjects = listFailed("ScaledObjects", "scaledobjects", err)
+				failed = true
 			} else {
-				deployments, err := pkg.GetDeployments(ctx, clientset, namespace)
+				scaledObjects = pkg.ScaledObjectsFor(allScaledObjects, deployments, statefulsets)
+				scaledObjectInfos, err := pkg.UpscaleScaledObjects(ctx, dynamicClient, scaledObjects, dryRun)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting deployments: %v\n", err)
-					os.Exit(1)
+					fmt.Fprintf(os.Stderr, "Error resuming scaledobjects: %v\n", err)
+					failed = true
+				}
+				result.ScaledObjects = pkg.ResourceGroup{
+					Type:      "ScaledObjects",
+					Resources: scaledObjectInfos,
 				}
-				deploymentInfos, err := pkg.UpscaleDeployments(ctx, clientset, deployments, dryRun)
+			}
+
+			// Deployments
+			if skipDeployments {
+				result.Deployments = pkg.ResourceGroup{Type: "Deployments", Skipped: true}
+			} else if result.Deployments.Error == "" {
+				deploymentInfos, err := pkg.UpscaleDeployments(ctx, clientset, pkg.DeploymentsWithoutScaledObjects(deployments, scaledObjects), dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error upscaling deployments: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.Deployments = pkg.ResourceGroup{
 					Type:      "Deployments",
@@ -58,16 +134,11 @@ var upCmd = &cobra.Command{
 			// StatefulSets
 			if skipStatefulsets {
 				result.StatefulSets = pkg.ResourceGroup{Type: "StatefulSets", Skipped: true}
-			} else {
-				statefulsets, err := pkg.GetStatefulSets(ctx, clientset, namespace)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting statefulsets: %v\n", err)
-					os.Exit(1)
-				}
-				statefulsetInfos, err := pkg.UpscaleStatefulSets(ctx, clientset, statefulsets, dryRun)
+			} else if result.StatefulSets.Error == "" {
+				statefulsetInfos, err := pkg.UpscaleStatefulSets(ctx, clientset, pkg.StatefulSetsWithoutScaledObjects(statefulsets, scaledObjects), dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error upscaling statefulsets: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.StatefulSets = pkg.ResourceGroup{
 					Type:      "StatefulSets",
@@ -78,16 +149,14 @@ var upCmd = &cobra.Command{
 			// DaemonSets
 			if skipDaemonsets {
 				result.DaemonSets = pkg.ResourceGroup{Type: "DaemonSets", Skipped: true}
+			} else if daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace, listOptions()); err != nil {
+				result.DaemonSets = listFailed("DaemonSets", "daemonsets", err)
+				failed = true
 			} else {
-				daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error getting daemonsets: %v\n", err)
-					os.Exit(1)
-				}
 				daemonsetInfos, err := pkg.UpscaleDaemonsets(ctx, clientset, daemonsets, dryRun)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error upscaling daemonsets: %v\n", err)
-					os.Exit(1)
+					failed = true
 				}
 				result.DaemonSets = pkg.ResourceGroup{
 					Type:      "DaemonSets",
@@ -95,18 +164,129 @@ var upCmd = &cobra.Command{
 				}
 			}
 
+			// CronJobs
+			if skipCronJobs {
+				result.CronJobs = pkg.ResourceGroup{Type: "CronJobs", Skipped: true}
+			} else if cronjobs, err := pkg.GetCronJobs(ctx, clientset, namespace, listOptions()); err != nil {
+				result.CronJobs = listFailed("CronJobs", "cronjobs", err)
+				failed = true
+			} else {
+				cronjobInfos, err := pkg.UpscaleCronJobs(ctx, clientset, cronjobs, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error resuming cronjobs: %v\n", err)
+					failed = true
+				}
+				result.CronJobs = pkg.ResourceGroup{
+					Type:      "CronJobs",
+					Resources: cronjobInfos,
+				}
+			}
+
+			// Jobs
+			if skipJobs {
+				result.Jobs = pkg.ResourceGroup{Type: "Jobs", Skipped: true}
+			} else if jobs, err := pkg.GetJobs(ctx, clientset, namespace, listOptions()); err != nil {
+				result.Jobs = listFailed("Jobs", "jobs", err)
+				failed = true
+			} else {
+				jobInfos, err := pkg.UpscaleJobs(ctx, clientset, jobs, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error resuming jobs: %v\n", err)
+					failed = true
+				}
+				result.Jobs = pkg.ResourceGroup{
+					Type:      "Jobs",
+					Resources: jobInfos,
+				}
+			}
+
+			// HorizontalPodAutoscalers targeting the deployments and statefulsets above
+			if skipHPAs {
+				result.HPAs = pkg.ResourceGroup{Type: "HorizontalPodAutoscalers", Skipped: true}
+			} else if hpas, err := pkg.GetHorizontalPodAutoscalers(ctx, clientset, namespace); err != nil {
+				result.HPAs = listFailed("HorizontalPodAutoscalers", "horizontal pod autoscalers", err)
+				failed = true
+			} else {
+				hpaInfos, err := pkg.UpscaleHorizontalPodAutoscalers(ctx, clientset, pkg.HorizontalPodAutoscalersFor(hpas, result), dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error unparking horizontal pod autoscalers: %v\n", err)
+					failed = true
+				}
+				result.HPAs = pkg.ResourceGroup{
+					Type:      "HorizontalPodAutoscalers",
+					Resources: hpaInfos,
+				}
+			}
+
+			// Resources exposing the scale subresource
+			for _, resource := range scalableResources {
+				objects, err := pkg.GetScalableObjects(ctx, dynamicClient, namespace, resource, listOptions())
+				if err != nil {
+					result.Scalables = append(result.Scalables, listFailed(resource.Name(), resource.Name(), err))
+					failed = true
+					continue
+				}
+				if len(objects.Items) == 0 {
+					continue
+				}
+				infos, err := pkg.UpscaleScalableObjects(ctx, dynamicClient, scaleClient, resource, objects, dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error upscaling %s: %v\n", resource.Name(), err)
+					failed = true
+				}
+				result.Scalables = append(result.Scalables, pkg.ResourceGroup{
+					Type:      resource.Name(),
+					Resources: infos,
+				})
+			}
+
+			if err := pkg.ClearAudit(ctx, dynamicClient, result, scalableResources, dryRun); err != nil {
+				fmt.Fprintf(os.Stderr, "Error clearing who downscaled the resources: %v\n", err)
+				failed = true
+			}
+
+			if ledger != nil && !dryRun {
+				forgetRestoredOrWarn(ctx, clientset, dynamicClient, namespace, ledger)
+			}
+
 			if err := printer.PrintNamespaceResult(result); err != nil {
 				fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
 				os.Exit(1)
 			}
 		}
 
+		if err := printer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing results: %v\n", err)
+			os.Exit(1)
+		}
+		if failed {
+			os.Exit(1)
+		}
+
 		if wait && !dryRun {
 			waitForResourcesOrFatal(ctx, clientset, false)
 		}
+
+		// Scheduled wake-ups are only cancelled once everything is up, so that their job retries otherwise.
+		// This comes last, since deleting the CronJob of a wake-up job running up stops it.
+		for _, namespace := range upscaled {
+			cancelWakeUpOrWarn(ctx, clientset, namespace)
+		}
 	},
 }
 
+// cancelWakeUpOrWarn removes the scheduled wake-up of a namespace
+func cancelWakeUpOrWarn(ctx context.Context, clientset kubernetes.Interface, namespace string) {
+	cancelled, err := pkg.DeleteWakeUp(ctx, clientset, namespace, dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not cancel the scheduled wake-up of namespace %s: %v\n", namespace, err)
+	}
+	if cancelled {
+		fmt.Fprintf(os.Stderr, "⏰ Cancelled the scheduled wake-up of namespace %s\n", namespace)
+	}
+}
+
 func init() {
+	upCmd.Flags().StringVar(&fromSnapshot, "from-snapshot", "", "Restore the state lost from the resources using a snapshot written by down --snapshot")
 	rootCmd.AddCommand(upCmd)
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+)
+
+var waitState string
+
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait for the deployments/statefulsets/daemonsets in the desired namespaces to be down or up, without scaling them",
+	Long: "Wait for the deployments, statefulsets and daemonsets in the desired namespaces to be down or up, without scaling them, " +
+		"e.g. after a GitOps tool did. Exits with 0 once they all reached the desired state, and with 1 when they did not " +
+		"before the timeout or waiting failed.\n\n" +
+		"Only deployments, statefulsets and daemonsets are waited for. Cronjobs, jobs, horizontal pod autoscalers, " +
+		"KEDA ScaledObjects and other resources exposing the scale subresource are not, so --skip-cronjobs, --skip-jobs, " +
+		"--skip-hpas, --skip-keda and --scale-subresources have no effect.",
+	Example: "szero wait --for down -n default\nszero wait --for up -n default --timeout 10m",
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if waitState != "down" && waitState != "up" {
+			fmt.Fprintf(os.Stderr, "Error: unknown state %q, expected one of down, up\n", waitState)
+			os.Exit(1)
+		}
+
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+		preflightOrFatal(ctx, clientset, pkg.Features{
+			Deployments:  !skipDeployments,
+			StatefulSets: !skipStatefulsets,
+			DaemonSets:   !skipDaemonsets,
+			Wait:         true,
+			ReadOnly:     true,
+		})
+
+		waitForResourcesOrFatal(ctx, clientset, waitState == "down")
+		fmt.Fprintln(os.Stderr, "✅ All resources reached the desired state")
+	},
+}
+
+func init() {
+	waitCmd.Flags().StringVar(&waitState, "for", "", "State to wait for: down or up")
+	if err := waitCmd.MarkFlagRequired("for"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := waitCmd.RegisterFlagCompletionFunc("for", cobra.FixedCompletions([]string{"down", "up"}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	rootCmd.AddCommand(waitCmd)
+}
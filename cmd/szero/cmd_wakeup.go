@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+)
+
+// wakeUpCmd is run by the CronJob created with down --for, inside the cluster
+var wakeUpCmd = &cobra.Command{
+	Use:    "wake-up",
+	Short:  "Bring the namespaces downscaled with down --for back up once their deadline passed",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		resolveNamespacesOrFatal(ctx, cmd, clientset)
+
+		var due []string
+		for _, namespace := range namespaces {
+			at, err := pkg.GetWakeUpDeadline(ctx, clientset, namespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting the wake-up deadline of namespace %s: %v\n", namespace, err)
+				os.Exit(1)
+			}
+			if at == nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Namespace %s has no scheduled wake-up\n", namespace)
+				continue
+			}
+			if time.Now().Before(*at) {
+				fmt.Fprintf(os.Stderr, "⏰ Namespace %s is not due to wake up before %s\n", namespace, at.Format(time.RFC3339))
+				continue
+			}
+			// Should bringing the namespace up fail, the job runs again in an hour
+			if err := pkg.RetryWakeUpHourly(ctx, clientset, namespace, *at); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not make the wake-up of namespace %s retry: %v\n", namespace, err)
+			}
+			due = append(due, namespace)
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		namespaces = due
+		upCmd.Run(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(wakeUpCmd)
+}
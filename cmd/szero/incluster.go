@@ -0,0 +1,46 @@
+package main
+
+import "github.com/jadolg/szero/pkg"
+
+// inClusterArgs are the arguments a CronJob runs a szero command with inside the cluster,
+// mirroring the flags szero was called with
+func inClusterArgs(command string, namespace string) []string {
+	args := []string{command, "--kubeconfig=", "--namespace=" + namespace}
+	skips := []struct {
+		flag string
+		set  bool
+	}{
+		{"skip-daemonsets", skipDaemonsets},
+		{"skip-statefulsets", skipStatefulsets},
+		{"skip-deployments", skipDeployments},
+		{"skip-cronjobs", skipCronJobs},
+		{"skip-jobs", skipJobs},
+		{"skip-hpas", skipHPAs},
+		{"skip-keda", skipKeda},
+		{"skip-ledger", skipLedger},
+	}
+	for _, skip := range skips {
+		if skip.set {
+			args = append(args, "--"+skip.flag)
+		}
+	}
+	if scaleSubresources {
+		args = append(args, "--scale-subresources")
+	}
+	if labelSelector != "" {
+		args = append(args, "--selector="+labelSelector)
+	}
+	if fieldSelector != "" {
+		args = append(args, "--field-selector="+fieldSelector)
+	}
+	return args
+}
+
+// inClusterFeatures are what the commands run with inClusterArgs do, for the Role they run with
+func inClusterFeatures(scalableResources []pkg.ScalableResource) pkg.Features {
+	features := selectedFeatures(scalableResources)
+	features.Wait = false
+	features.ReadOnly = false
+	features.WakeUp = false
+	return features
+}
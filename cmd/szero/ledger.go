@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// selectedLedgerOrWarn returns the ledger entries of the resources selected by the flags, or nil when there are none
+// or the ledger is skipped or can't be read
+func selectedLedgerOrWarn(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, namespace string, scalableResources []pkg.ScalableResource) *pkg.Ledger {
+	if skipLedger {
+		return nil
+	}
+	ledger, err := pkg.GetLedger(ctx, clientset, namespace)
+	if err == nil && ledger != nil {
+		ledger, err = ledger.Select(ctx, dynamicClient, namespace, selectedFeatures(scalableResources), listOptions())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read the ledger in namespace %s: %v\n", namespace, err)
+		return nil
+	}
+	return ledger
+}
+
+// forgetRestoredOrWarn removes the ledger entries of the resources that were brought back up
+func forgetRestoredOrWarn(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, namespace string, selection *pkg.Ledger) {
+	if _, err := pkg.ForgetRestored(ctx, clientset, dynamicClient, namespace, selection.Entries); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not update the ledger in namespace %s: %v\n", namespace, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/fang"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
 )
@@ -28,13 +29,36 @@ var (
 	kubecontext string
 	namespaces  []string
 
+	as       string
+	asGroups []string
+	token    string
+	server   string
+
+	namespaceSelector string
+	allNamespaces     bool
+
 	skipDaemonsets   bool
 	skipStatefulsets bool
 	skipDeployments  bool
+	skipCronJobs     bool
+	skipJobs         bool
+	skipHPAs         bool
+	skipKeda         bool
+	skipLedger       bool
+
+	scaleSubresources bool
+
+	labelSelector string
+	fieldSelector string
+
+	wait          bool
+	dryRun        bool
+	timeout       time.Duration
+	skipPreflight bool
+	failOn        []string
+	waitForPods   bool
 
-	wait    bool
-	dryRun  bool
-	timeout time.Duration
+	output string
 
 	rootCmd = &cobra.Command{
 		Use:   getApplicationName(),
@@ -60,24 +84,63 @@ func getDefaultKubeconfigPath() string {
 	return "kubeconfig"
 }
 
+// listOptions selects the workloads to scale and wait for according to the selector flags
+func listOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+	}
+}
+
+// authOverrides replaces the identity and API server of the kubeconfig according to the auth flags
+func authOverrides() pkg.AuthOverrides {
+	return pkg.AuthOverrides{
+		As:       as,
+		AsGroups: asGroups,
+		Token:    token,
+		Server:   server,
+	}
+}
+
 func init() {
 	defaultContext, defaultNamespace := pkg.GetDefaultKubernetesContextAndNamespace(getDefaultKubeconfigPath())
 	rootCmd.PersistentFlags().StringVarP(&kubeconfig, "kubeconfig", "k", getDefaultKubeconfigPath(), "Path to kubeconfig file")
 	rootCmd.PersistentFlags().StringVarP(&kubecontext, "context", "c", defaultContext, "Kubernetes context")
-	rootCmd.PersistentFlags().StringSliceVarP(&namespaces, "namespace", "n", []string{defaultNamespace}, "Kubernetes namespace")
+	rootCmd.PersistentFlags().StringVar(&as, "as", "", "Username to impersonate for the operation")
+	rootCmd.PersistentFlags().StringArrayVar(&asGroups, "as-group", []string{}, "Group to impersonate for the operation, can be repeated to specify multiple groups")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "Bearer token for authentication to the API server")
+	rootCmd.PersistentFlags().StringVar(&server, "server", "", "The address and port of the Kubernetes API server")
+	rootCmd.PersistentFlags().StringSliceVarP(&namespaces, "namespace", "n", []string{defaultNamespace}, "Kubernetes namespace, glob (e.g. preview-*) or regular expression between slashes (e.g. /^pr-[0-9]+$/)")
+	rootCmd.PersistentFlags().StringVar(&namespaceSelector, "namespace-selector", "", "Only use namespaces matching this label selector (e.g. env=preview)")
+	rootCmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Use all namespaces except kube-system, kube-public, kube-node-lease, and local-path-storage")
+	rootCmd.MarkFlagsMutuallyExclusive("namespace", "all-namespaces")
 
 	rootCmd.PersistentFlags().BoolVarP(&skipDaemonsets, "skip-daemonsets", "d", false, "Skip daemonsets")
 	rootCmd.PersistentFlags().BoolVarP(&skipStatefulsets, "skip-statefulsets", "s", false, "Skip statefulsets")
 	rootCmd.PersistentFlags().BoolVarP(&skipDeployments, "skip-deployments", "p", false, "Skip deployments")
+	rootCmd.PersistentFlags().BoolVar(&skipCronJobs, "skip-cronjobs", false, "Skip cronjobs")
+	rootCmd.PersistentFlags().BoolVar(&skipJobs, "skip-jobs", false, "Skip jobs")
+	rootCmd.PersistentFlags().BoolVar(&skipHPAs, "skip-hpas", false, "Skip parking horizontal pod autoscalers targeting the scaled workloads")
+	rootCmd.PersistentFlags().BoolVar(&skipKeda, "skip-keda", false, "Skip pausing KEDA ScaledObjects and scale their targets directly instead")
+	rootCmd.PersistentFlags().BoolVar(&skipLedger, "skip-ledger", false, "Skip recording the state needed to bring the namespaces back up in a szero-ledger ConfigMap on down, and restoring from it on up")
+	rootCmd.PersistentFlags().BoolVar(&scaleSubresources, "scale-subresources", false, "Also scale any other namespaced resource exposing the scale subresource (e.g. Argo Rollouts)")
+
+	rootCmd.PersistentFlags().StringVarP(&labelSelector, "selector", "l", "", "Only scale workloads matching this label selector (e.g. tier=backend)")
+	rootCmd.PersistentFlags().StringVar(&fieldSelector, "field-selector", "", "Only scale workloads matching this field selector (e.g. metadata.name=web)")
 
 	rootCmd.PersistentFlags().BoolVarP(&wait, "wait", "w", false, "Wait for all resources to reconcile into the desired state")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "r", false, "Run in dry-run mode (no changes will be made)")
+	rootCmd.PersistentFlags().StringSliceVar(&failOn, "fail-on", []string{"ImagePullBackOff", "ErrImageNeverPull", "InvalidImageName"}, "Stop waiting for workloads to come back up as soon as one of their pods is stuck for one of these reasons (e.g. CrashLoopBackOff, CreateContainerConfigError, Unschedulable), empty to always wait for the timeout")
+	rootCmd.PersistentFlags().BoolVar(&waitForPods, "wait-for-pods", false, "When waiting for workloads to go down, also wait for all their pods to be gone, including terminating ones (implies --wait for down)")
+	rootCmd.PersistentFlags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip checking that all the needed permissions are granted before changing anything")
 	rootCmd.PersistentFlags().DurationVarP(&timeout, "timeout", "t", 5*time.Minute, "Timeout for waiting for resources to reconcile into the desired state")
 
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "tree", "Output format: tree, json, or yaml")
+
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 	err := rootCmd.RegisterFlagCompletionFunc("namespace", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		ctx := context.Background()
-		clientset, err := pkg.GetClientset(kubeconfig, kubecontext)
+		clientset, err := pkg.GetClientset(kubeconfig, kubecontext, authOverrides())
 		if err != nil {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		}
@@ -88,6 +151,11 @@ func init() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	err = rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"tree", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	err = rootCmd.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		contexts, err := pkg.GetKubernetesContexts(kubeconfig)
 		if err != nil {
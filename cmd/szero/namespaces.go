@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceSelection describes the namespaces selected by the namespace flags.
+// The default namespace only applies when neither a namespace selector nor all namespaces are requested.
+func namespaceSelection(cmd *cobra.Command) pkg.NamespaceSelection {
+	selection := pkg.NamespaceSelection{
+		Selector: namespaceSelector,
+		All:      allNamespaces,
+	}
+	if cmd.Flags().Changed("namespace") || (namespaceSelector == "" && !allNamespaces) {
+		selection.Patterns = namespaces
+	}
+	return selection
+}
+
+// resolveNamespacesOrFatal expands the namespace flags into the namespaces to operate on
+func resolveNamespacesOrFatal(ctx context.Context, cmd *cobra.Command, clientset kubernetes.Interface) {
+	resolved, err := pkg.ResolveNamespaces(ctx, clientset, namespaceSelection(cmd))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving namespaces: %v\n", err)
+		os.Exit(1)
+	}
+	if len(resolved) == 0 {
+		fmt.Fprintln(os.Stderr, "⚠️  No namespaces matched the selection")
+	}
+	namespaces = resolved
+}
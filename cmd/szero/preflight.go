@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// selectedFeatures describes what the command is about to do according to its flags
+func selectedFeatures(scalableResources []pkg.ScalableResource) pkg.Features {
+	return pkg.Features{
+		Deployments:   !skipDeployments,
+		StatefulSets:  !skipStatefulsets,
+		DaemonSets:    !skipDaemonsets,
+		CronJobs:      !skipCronJobs,
+		Jobs:          !skipJobs,
+		HPAs:          !skipHPAs,
+		ScaledObjects: !skipKeda,
+		Scalables:     scalableResources,
+		Wait:          wait && !dryRun,
+		ReadOnly:      dryRun,
+		Ledger:        !skipLedger,
+		WakeUp:        downFor > 0 && !dryRun,
+	}
+}
+
+// servedFeaturesOrWarn turns off the optional kinds the cluster does not serve, such as KEDA ScaledObjects,
+// keeping them all when discovery fails
+func servedFeaturesOrWarn(clientset kubernetes.Interface, features pkg.Features) pkg.Features {
+	served, err := pkg.ServedFeatures(clientset.Discovery(), features)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not discover whether HPAs and KEDA ScaledObjects are served, requiring their permissions anyway: %v\n", err)
+		return features
+	}
+	return served
+}
+
+// preflightOrFatal makes sure the current user is allowed everything the command is about to do in the
+// namespaces before anything is changed, printing what is missing otherwise. Excluded namespaces are left
+// out, since nothing is done in them, and namespaces that can't be read are checked, so that reading them
+// is reported as missing. Kinds the cluster does not serve are not checked.
+func preflightOrFatal(ctx context.Context, clientset kubernetes.Interface, features pkg.Features) {
+	if skipPreflight {
+		return
+	}
+
+	var targets []string
+	for _, namespace := range namespaces {
+		excluded, err := pkg.IsNamespaceExcluded(ctx, clientset, namespace)
+		if err != nil && !apierrors.IsForbidden(err) {
+			fmt.Fprintf(os.Stderr, "Error checking namespace %s: %v\n", namespace, err)
+			os.Exit(1)
+		}
+		if !excluded {
+			targets = append(targets, namespace)
+		}
+	}
+
+	missing, err := pkg.CheckPermissions(ctx, clientset, targets, pkg.RequiredRules(servedFeaturesOrWarn(clientset, features)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking permissions: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Use --skip-preflight to go ahead without checking them")
+		os.Exit(1)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: missing permissions, nothing was changed")
+		if err := pkg.WritePermissionTable(os.Stderr, missing); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing permissions: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/scale"
+)
+
+// getScalableResourcesOrFatal builds the client needed to scale resources through the scale subresource
+// and discovers which resources expose it. Nothing is discovered unless --scale-subresources is set.
+func getScalableResourcesOrFatal(clientset kubernetes.Interface) (scale.ScalesGetter, []pkg.ScalableResource) {
+	if !scaleSubresources {
+		return nil, nil
+	}
+
+	scaleClient, err := pkg.GetScaleClient(kubeconfig, kubecontext, authOverrides(), clientset.Discovery())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	resources, err := pkg.GetScalableResources(clientset.Discovery())
+	if err != nil {
+		if resources == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Some API groups could not be discovered: %v\n", err)
+	}
+	return scaleClient, resources
+}
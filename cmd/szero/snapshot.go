@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	"github.com/spf13/cobra"
+)
+
+// readSnapshotOrFatal reads the snapshot passed with --from-snapshot, or returns an empty one when none is given.
+// Unless namespaces are selected explicitly, the namespaces in the snapshot are the ones brought back up.
+func readSnapshotOrFatal(cmd *cobra.Command) *pkg.Snapshot {
+	if fromSnapshot == "" {
+		return pkg.NewSnapshot()
+	}
+
+	snapshot, err := pkg.ReadSnapshotFile(fromSnapshot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !cmd.Flags().Changed("namespace") && namespaceSelector == "" && !allNamespaces {
+		namespaces = snapshot.NamespaceNames()
+	}
+	return snapshot
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/jadolg/szero/pkg"
 	"k8s.io/client-go/kubernetes"
@@ -11,15 +13,27 @@ import (
 
 func waitForResourcesOrFatal(ctx context.Context, clientset kubernetes.Interface, downscaled bool) {
 	waitFor := len(namespaces) * 3 // deployments, statefulsets, and daemonsets per namespace
-	errors := make(chan error, 1)
+	errors := make(chan error, waitFor)
 	done := make(chan bool, waitFor)
 
-	fmt.Printf("⏳ Waiting for all resources to reach the desired state in %d namespaces (timeout %v)\n", len(namespaces), timeout)
+	options := pkg.WaitOptions{
+		Timeout:    timeout,
+		Downscaled: downscaled,
+		FailOn:     slices.DeleteFunc(slices.Clone(failOn), func(reason string) bool { return reason == "" }),
+		PodsGone:   waitForPods,
+	}
+
+	fmt.Fprintf(os.Stderr, "⏳ Waiting for all resources to reach the desired state in %d namespaces (timeout %v)\n", len(namespaces), timeout)
 
 	for _, namespace := range namespaces {
+		if isNamespaceExcludedOrFatal(ctx, clientset, namespace) {
+			waitFor -= 3
+			continue
+		}
+
 		if !skipDeployments {
 			go func(errors chan error) {
-				waitForDeployments(ctx, clientset, namespace, downscaled, done, errors)
+				waitForDeployments(ctx, clientset, namespace, options, done, errors)
 			}(errors)
 		} else {
 			waitFor--
@@ -27,7 +41,7 @@ func waitForResourcesOrFatal(ctx context.Context, clientset kubernetes.Interface
 
 		if !skipStatefulsets {
 			go func(errors chan error) {
-				waitForStatefulSets(ctx, clientset, namespace, downscaled, done, errors)
+				waitForStatefulSets(ctx, clientset, namespace, options, done, errors)
 			}(errors)
 		} else {
 			waitFor--
@@ -35,36 +49,43 @@ func waitForResourcesOrFatal(ctx context.Context, clientset kubernetes.Interface
 
 		if !skipDaemonsets {
 			go func(errors chan error) {
-				waitForDaemonSets(ctx, clientset, namespace, downscaled, done, errors)
+				waitForDaemonSets(ctx, clientset, namespace, options, done, errors)
 			}(errors)
 		} else {
 			waitFor--
 		}
 	}
 
-	for {
+	// Every kind in every namespace gets to finish, so that all the timeouts are reported together
+	failed := false
+	for waitFor > 0 {
 		select {
 		case err := <-errors:
-			if err != nil {
+			var timeoutErr *pkg.WaitTimeoutError
+			if goerrors.As(err, &timeoutErr) {
+				if err := pkg.WriteWaitDiagnostics(os.Stderr, timeoutErr); err != nil {
+					fmt.Fprintf(os.Stderr, "Error printing diagnostics: %v\n", err)
+				}
+			} else {
 				fmt.Fprintf(os.Stderr, "Error waiting for resources to reach desired state: %v\n", err)
-				os.Exit(1)
 			}
+			failed = true
 		case <-done:
-			waitFor--
-			if waitFor == 0 {
-				return
-			}
 		}
+		waitFor--
+	}
+	if failed {
+		os.Exit(1)
 	}
 }
 
-func waitForDaemonSets(ctx context.Context, clientset kubernetes.Interface, namespace string, downscaled bool, done chan bool, errors chan error) {
-	daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace)
+func waitForDaemonSets(ctx context.Context, clientset kubernetes.Interface, namespace string, options pkg.WaitOptions, done chan bool, errors chan error) {
+	daemonsets, err := pkg.GetDaemonsets(ctx, clientset, namespace, listOptions())
 	if err != nil {
 		errors <- err
 		return
 	}
-	err = pkg.WaitForDaemonSets(ctx, clientset, daemonsets, timeout, downscaled)
+	err = pkg.WaitForDaemonSets(ctx, clientset, daemonsets, options)
 	if err != nil {
 		errors <- fmt.Errorf("could not wait for DaemonSets in namespace %s: %w", namespace, err)
 		return
@@ -72,13 +93,13 @@ func waitForDaemonSets(ctx context.Context, clientset kubernetes.Interface, name
 	done <- true
 }
 
-func waitForStatefulSets(ctx context.Context, clientset kubernetes.Interface, namespace string, downscaled bool, done chan bool, errors chan error) {
-	statefulsets, err := pkg.GetStatefulSets(ctx, clientset, namespace)
+func waitForStatefulSets(ctx context.Context, clientset kubernetes.Interface, namespace string, options pkg.WaitOptions, done chan bool, errors chan error) {
+	statefulsets, err := pkg.GetStatefulSets(ctx, clientset, namespace, listOptions())
 	if err != nil {
 		errors <- err
 		return
 	}
-	err = pkg.WaitForStatefulSets(ctx, clientset, statefulsets, timeout, downscaled)
+	err = pkg.WaitForStatefulSets(ctx, clientset, statefulsets, options)
 	if err != nil {
 		errors <- fmt.Errorf("could not wait for StatefulSets in namespace %s: %w", namespace, err)
 		return
@@ -86,13 +107,13 @@ func waitForStatefulSets(ctx context.Context, clientset kubernetes.Interface, na
 	done <- true
 }
 
-func waitForDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, downscaled bool, done chan bool, errors chan error) {
-	deployments, err := pkg.GetDeployments(ctx, clientset, namespace)
+func waitForDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, options pkg.WaitOptions, done chan bool, errors chan error) {
+	deployments, err := pkg.GetDeployments(ctx, clientset, namespace, listOptions())
 	if err != nil {
 		errors <- err
 		return
 	}
-	err = pkg.WaitForDeployments(ctx, clientset, deployments, timeout, downscaled)
+	err = pkg.WaitForDeployments(ctx, clientset, deployments, options)
 	if err != nil {
 		errors <- fmt.Errorf("could not wait for Deployments in namespace %s: %w", namespace, err)
 		return
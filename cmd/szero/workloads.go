@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jadolg/szero/pkg"
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// getWorkloads lists the selected deployments and statefulsets up front, so that the
+// resources controlling them (like KEDA ScaledObjects) can be matched against the selection.
+// Skipped kinds and kinds that cannot be listed are returned as empty lists, the latter with
+// the error recorded in the result.
+func getWorkloads(ctx context.Context, clientset kubernetes.Interface, namespace string, result *pkg.NamespaceResult) (*v1.DeploymentList, *v1.StatefulSetList, bool) {
+	deployments := &v1.DeploymentList{}
+	statefulsets := &v1.StatefulSetList{}
+	listed := true
+
+	if !skipDeployments {
+		list, err := pkg.GetDeployments(ctx, clientset, namespace, listOptions())
+		if err != nil {
+			result.Deployments = listFailed("Deployments", "deployments", err)
+			listed = false
+		} else {
+			deployments = list
+		}
+	}
+
+	if !skipStatefulsets {
+		list, err := pkg.GetStatefulSets(ctx, clientset, namespace, listOptions())
+		if err != nil {
+			result.StatefulSets = listFailed("StatefulSets", "statefulsets", err)
+			listed = false
+		} else {
+			statefulsets = list
+		}
+	}
+
+	return deployments, statefulsets, listed
+}
+
+// listFailed reports resources that could not be listed. The command goes on with the other kinds and
+// namespaces, so that their results are still printed, and fails in the end.
+func listFailed(groupType string, noun string, err error) pkg.ResourceGroup {
+	fmt.Fprintf(os.Stderr, "Error getting %s: %v\n", noun, err)
+	return pkg.ResourceGroup{Type: groupType, Error: err.Error()}
+}
+
+// isNamespaceExcludedOrFatal reports whether a namespace opted out of szero through the exclude annotation
+func isNamespaceExcludedOrFatal(ctx context.Context, clientset kubernetes.Interface, namespace string) bool {
+	excluded, err := pkg.IsNamespaceExcluded(ctx, clientset, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking namespace %s: %v\n", namespace, err)
+		os.Exit(1)
+	}
+	return excluded
+}
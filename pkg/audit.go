@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const downscaledAtAnnotation = "szero/downscaled-at"
+const downscaledByAnnotation = "szero/downscaled-by"
+const reasonAnnotation = "szero/reason"
+
+// Audit records who downscaled resources, when, and why
+type Audit struct {
+	At     time.Time `json:"at"`
+	By     string    `json:"by"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// String describes the audit in a single line, e.g. "by alice at 2025-01-02T15:04:05Z: release freeze"
+func (a Audit) String() string {
+	description := fmt.Sprintf("by %s at %s", a.By, a.At.Format(time.RFC3339))
+	if a.Reason != "" {
+		description = fmt.Sprintf("%s: %s", description, a.Reason)
+	}
+	return description
+}
+
+// annotations returns the annotations recording the audit on a resource
+func (a Audit) annotations() map[string]*string {
+	at := a.At.UTC().Format(time.RFC3339)
+	annotations := map[string]*string{
+		downscaledAtAnnotation: &at,
+		downscaledByAnnotation: &a.By,
+		reasonAnnotation:       nil,
+	}
+	if a.Reason != "" {
+		annotations[reasonAnnotation] = &a.Reason
+	}
+	return annotations
+}
+
+// auditFromAnnotations reads the audit recorded on a resource, if there is one
+func auditFromAnnotations(annotations map[string]string) *Audit {
+	by, found := annotations[downscaledByAnnotation]
+	if !found {
+		return nil
+	}
+	at, _ := time.Parse(time.RFC3339, annotations[downscaledAtAnnotation])
+	return &Audit{At: at, By: by, Reason: annotations[reasonAnnotation]}
+}
+
+// RecordAudit annotates the resources scaled down in result with who downscaled them, when, and why
+func RecordAudit(ctx context.Context, dynamicClient dynamic.Interface, result NamespaceResult, scalableResources []ScalableResource, audit Audit, dryRun bool) error {
+	return patchScaledAnnotations(ctx, dynamicClient, result, scalableResources, audit.annotations(), dryRun)
+}
+
+// ClearAudit removes the audit annotations from the resources scaled up in result
+func ClearAudit(ctx context.Context, dynamicClient dynamic.Interface, result NamespaceResult, scalableResources []ScalableResource, dryRun bool) error {
+	return patchScaledAnnotations(ctx, dynamicClient, result, scalableResources, map[string]*string{
+		downscaledAtAnnotation: nil,
+		downscaledByAnnotation: nil,
+		reasonAnnotation:       nil,
+	}, dryRun)
+}
+
+// scaledGroup pairs the results of a kind of resource with the API resource to patch them through
+type scaledGroup struct {
+	resource schema.GroupVersionResource
+	group    ResourceGroup
+}
+
+func patchScaledAnnotations(ctx context.Context, dynamicClient dynamic.Interface, result NamespaceResult, scalableResources []ScalableResource, annotations map[string]*string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+
+	groups := []scaledGroup{
+		{deploymentsResource, result.Deployments},
+		{statefulSetsResource, result.StatefulSets},
+		{daemonSetsResource, result.DaemonSets},
+		{cronJobsResource, result.CronJobs},
+		{jobsResource, result.Jobs},
+		{hpasResource, result.HPAs},
+		{scaledObjectsResource, result.ScaledObjects},
+	}
+	for _, group := range result.Scalables {
+		for _, r := range scalableResources {
+			if r.Name() == group.Type {
+				groups = append(groups, scaledGroup{r.Resource, group})
+			}
+		}
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+	var resultError error
+	for _, g := range groups {
+		for _, info := range g.group.Resources {
+			if !info.Scaled {
+				continue
+			}
+			_, err := dynamicClient.Resource(g.resource).Namespace(result.Namespace).Patch(ctx, info.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			if err != nil {
+				resultError = errors.Join(fmt.Errorf("error annotating %s %s: %w", g.resource.Resource, info.Name, err), resultError)
+			}
+		}
+	}
+	return resultError
+}
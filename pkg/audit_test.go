@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordAndClearAudit(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := newSnapshotDynamicClient(
+		newUnstructured("apps/v1", "Deployment", "web", map[string]string{replicasAnnotation: "3"}),
+		newUnstructured("apps/v1", "Deployment", "idle", map[string]string{replicasAnnotation: "1"}),
+		newRollout("canary", nil),
+	)
+	result := NamespaceResult{
+		Namespace: "default",
+		Deployments: ResourceGroup{Type: "Deployments", Resources: []ScaleInfo{
+			{Name: "web", Replicas: 3, Scaled: true},
+			{Name: "idle", Warning: "already downscaled"},
+		}},
+		Scalables: []ResourceGroup{{Type: rollouts.Name(), Resources: []ScaleInfo{{Name: "canary", Scaled: true}}}},
+	}
+	audit := Audit{At: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC), By: "alice", Reason: "release freeze"}
+
+	assert.NoError(t, RecordAudit(ctx, dynamicClient, result, []ScalableResource{rollouts}, audit, false))
+
+	web, err := dynamicClient.Resource(deploymentsResource).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		replicasAnnotation:     "3",
+		downscaledAtAnnotation: "2025-01-02T15:04:05Z",
+		downscaledByAnnotation: "alice",
+		reasonAnnotation:       "release freeze",
+	}, web.GetAnnotations())
+	assert.Equal(t, &audit, auditFromAnnotations(web.GetAnnotations()))
+
+	idle, err := dynamicClient.Resource(deploymentsResource).Namespace("default").Get(ctx, "idle", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, auditFromAnnotations(idle.GetAnnotations()))
+
+	canary, err := dynamicClient.Resource(rollouts.Resource).Namespace("default").Get(ctx, "canary", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", canary.GetAnnotations()[downscaledByAnnotation])
+
+	assert.NoError(t, ClearAudit(ctx, dynamicClient, result, []ScalableResource{rollouts}, false))
+	web, err = dynamicClient.Resource(deploymentsResource).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{replicasAnnotation: "3"}, web.GetAnnotations())
+}
+
+func TestRecordAuditInDryRun(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := newSnapshotDynamicClient(newUnstructured("apps/v1", "Deployment", "web", nil))
+	result := NamespaceResult{
+		Namespace:   "default",
+		Deployments: ResourceGroup{Type: "Deployments", Resources: []ScaleInfo{{Name: "web", Scaled: true}}},
+	}
+
+	assert.NoError(t, RecordAudit(ctx, dynamicClient, result, nil, Audit{By: "alice"}, true))
+	web, err := dynamicClient.Resource(deploymentsResource).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, web.GetAnnotations())
+}
+
+func TestAuditString(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, "by alice at 2025-01-02T15:04:05Z", Audit{At: at, By: "alice"}.String())
+	assert.Equal(t, "by alice at 2025-01-02T15:04:05Z: release freeze", Audit{At: at, By: "alice", Reason: "release freeze"}.String())
+}
@@ -1,15 +1,25 @@
 package pkg
 
-import "k8s.io/client-go/tools/clientcmd"
+import (
+	"os"
+	"strings"
 
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serviceAccountNamespaceFile holds the namespace of the pod szero runs in, when it runs inside the cluster
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// GetDefaultKubernetesContextAndNamespace returns the current context of a kubeconfig file and its namespace.
+// Without a current context, the namespace is the one of the pod szero runs in, or "default" outside the cluster.
 func GetDefaultKubernetesContextAndNamespace(kubeconfig string) (string, string) {
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
 		&clientcmd.ConfigOverrides{
 			CurrentContext: "",
 		}).RawConfig()
-	if err != nil {
-		return "", "default"
+	if err != nil || config.CurrentContext == "" {
+		return "", InClusterNamespace()
 	}
 	namespace := "default"
 	if _, found := config.Contexts[config.CurrentContext]; found && config.Contexts[config.CurrentContext].Namespace != "" {
@@ -18,6 +28,15 @@ func GetDefaultKubernetesContextAndNamespace(kubeconfig string) (string, string)
 	return config.CurrentContext, namespace
 }
 
+// InClusterNamespace returns the namespace of the pod szero runs in, or "default" outside the cluster
+func InClusterNamespace() string {
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "default"
+	}
+	return strings.TrimSpace(string(data))
+}
+
 func GetKubernetesContexts(kubeconfig string) ([]string, error) {
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
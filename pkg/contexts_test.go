@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDefaultKubernetesContextAndNamespace(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: user
+contexts:
+- name: staging
+  context:
+    cluster: cluster
+    user: user
+    namespace: web
+current-context: staging
+`), 0o600))
+	namespaceFile := filepath.Join(dir, "namespace")
+	assert.NoError(t, os.WriteFile(namespaceFile, []byte("szero-system\n"), 0o600))
+
+	original := serviceAccountNamespaceFile
+	t.Cleanup(func() { serviceAccountNamespaceFile = original })
+
+	tests := []struct {
+		name              string
+		kubeconfig        string
+		namespaceFile     string
+		expectedContext   string
+		expectedNamespace string
+	}{
+		{
+			name:              "When there is a kubeconfig then its current context and namespace are used",
+			kubeconfig:        kubeconfig,
+			namespaceFile:     namespaceFile,
+			expectedContext:   "staging",
+			expectedNamespace: "web",
+		},
+		{
+			name:              "When running in a pod without a kubeconfig then the namespace of the pod is used",
+			kubeconfig:        filepath.Join(dir, "missing"),
+			namespaceFile:     namespaceFile,
+			expectedContext:   "",
+			expectedNamespace: "szero-system",
+		},
+		{
+			name:              "When running outside the cluster without a kubeconfig then the default namespace is used",
+			kubeconfig:        filepath.Join(dir, "missing"),
+			namespaceFile:     filepath.Join(dir, "missing-namespace"),
+			expectedContext:   "",
+			expectedNamespace: "default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			serviceAccountNamespaceFile = test.namespaceFile
+			context, namespace := GetDefaultKubernetesContextAndNamespace(test.kubeconfig)
+			assert.Equal(t, test.expectedContext, context)
+			assert.Equal(t, test.expectedNamespace, namespace)
+		})
+	}
+}
+
+func TestKubeconfigExists(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	assert.False(t, kubeconfigExists(""))
+	assert.False(t, kubeconfigExists(kubeconfig))
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte{}, 0o600))
+	assert.True(t, kubeconfigExists(kubeconfig))
+}
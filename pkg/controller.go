@@ -0,0 +1,331 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/utils/clock"
+)
+
+// scheduleStateAnnotation records on a namespace the state the controller last put it in
+const scheduleStateAnnotation = "szero/schedule-state"
+
+const (
+	scheduleStateAsleep = "asleep"
+	scheduleStateAwake  = "awake"
+)
+
+// ControllerOperator is recorded as the one downscaling namespaces on schedule
+const ControllerOperator = "szero controller"
+
+// Controller downscales and upscales namespaces according to the schedules in their annotations
+type Controller struct {
+	Clientset     kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Clock         clock.WithTicker
+	Logger        *slog.Logger
+	// Interval is how often the schedules are checked
+	Interval time.Duration
+	// NamespaceSelector is a label selector limiting the namespaces the controller manages
+	NamespaceSelector string
+	// Features selects the kinds of resources scaled in each namespace, only the kinds are looked at
+	Features Features
+	// ListOptions selects the workloads scaled in each namespace
+	ListOptions metav1.ListOptions
+	// ScaleClient scales the Scalables of Features
+	ScaleClient scale.ScalesGetter
+	DryRun      bool
+
+	// dryRunStates stands in for the schedule-state annotations in dry-run mode, so that a schedule is only
+	// applied once per state change as it would be otherwise
+	dryRunStates map[string]string
+}
+
+// Run checks the schedules every interval until the context is done
+func (c *Controller) Run(ctx context.Context) {
+	ticker := c.Clock.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		// Errors are logged as they happen, the next pass retries
+		_ = c.Reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// RunWithLeaderElection runs the controller only while holding the given Lease, so that a single replica acts at a time.
+// It returns when the context is done or the lease is lost.
+func (c *Controller) RunWithLeaderElection(ctx context.Context, leaseNamespace string, leaseName string, identity string) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+		Client:     c.Clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				c.Logger.Info("Started leading", "lease", leaseNamespace+"/"+leaseName, "identity", identity)
+				c.Run(ctx)
+			},
+			OnStoppedLeading: func() {
+				c.Logger.Info("Stopped leading", "lease", leaseNamespace+"/"+leaseName, "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					c.Logger.Info("Following another leader", "lease", leaseNamespace+"/"+leaseName, "leader", leader)
+				}
+			},
+		},
+	})
+}
+
+// Reconcile puts every namespace with a schedule into the state its schedule asks for at the current time.
+// Namespaces are only scaled when their schedule changes state, so a namespace brought up by hand stays up
+// until its next sleep.
+func (c *Controller) Reconcile(ctx context.Context) error {
+	namespaces, err := c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: c.NamespaceSelector})
+	if err != nil {
+		c.Logger.Error("Could not list namespaces", "error", err)
+		return fmt.Errorf("error getting namespaces: %w", err)
+	}
+
+	var resultError error
+	for _, ns := range namespaces.Items {
+		if err := c.reconcileNamespace(ctx, &ns); err != nil {
+			c.Logger.Error("Could not apply schedule", "namespace", ns.Name, "error", err)
+			resultError = errors.Join(fmt.Errorf("error applying schedule of namespace %s: %w", ns.Name, err), resultError)
+		}
+	}
+	return resultError
+}
+
+func (c *Controller) reconcileNamespace(ctx context.Context, ns *corev1.Namespace) error {
+	schedule, err := ParseSchedule(ns.Annotations)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return nil
+	}
+	if IsExcluded(ns) {
+		c.Logger.Debug("Skipping namespace", "namespace", ns.Name, "reason", ExcludedReason)
+		return nil
+	}
+
+	now := c.Clock.Now()
+	desired := scheduleStateAwake
+	if schedule.Asleep(now) {
+		desired = scheduleStateAsleep
+	}
+	current, found := ns.Annotations[scheduleStateAnnotation]
+	if state, recorded := c.dryRunStates[ns.Name]; recorded {
+		current, found = state, true
+	}
+	if current == desired {
+		return nil
+	}
+	if !found && desired == scheduleStateAwake {
+		// The schedule is new and the namespace is meant to be up, there is nothing to bring back
+		c.Logger.Info("Namespace is awake", "namespace", ns.Name)
+		return c.recordScheduleState(ctx, ns.Name, desired)
+	}
+
+	c.Logger.Info("Applying schedule", "namespace", ns.Name, "state", desired, "dryRun", c.DryRun)
+	result, err := c.scaleNamespace(ctx, ns.Name, desired == scheduleStateAsleep, now)
+	c.Logger.Info("Applied schedule", "namespace", ns.Name, "state", desired, "scaled", scaledCount(result))
+	if err != nil {
+		// Keep the previous state so that the next pass tries again
+		return err
+	}
+	return c.recordScheduleState(ctx, ns.Name, desired)
+}
+
+func (c *Controller) recordScheduleState(ctx context.Context, namespace string, state string) error {
+	if c.DryRun {
+		if c.dryRunStates == nil {
+			c.dryRunStates = map[string]string{}
+		}
+		c.dryRunStates[namespace] = state
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]string{scheduleStateAnnotation: state}},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.Clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error recording schedule state: %w", err)
+	}
+	return nil
+}
+
+// namespaceScaler holds either the Downscale* or the Upscale* function of each kind of resource
+type namespaceScaler struct {
+	scaledObjects func(context.Context, dynamic.Interface, *unstructured.UnstructuredList, bool) ([]ScaleInfo, error)
+	deployments   func(context.Context, kubernetes.Interface, *v1.DeploymentList, bool) ([]ScaleInfo, error)
+	statefulsets  func(context.Context, kubernetes.Interface, *v1.StatefulSetList, bool) ([]ScaleInfo, error)
+	daemonsets    func(context.Context, kubernetes.Interface, *v1.DaemonSetList, bool) ([]ScaleInfo, error)
+	cronjobs      func(context.Context, kubernetes.Interface, *batchv1.CronJobList, bool) ([]ScaleInfo, error)
+	jobs          func(context.Context, kubernetes.Interface, *batchv1.JobList, bool) ([]ScaleInfo, error)
+	hpas          func(context.Context, kubernetes.Interface, *autoscalingv2.HorizontalPodAutoscalerList, bool) ([]ScaleInfo, error)
+	scalables     func(context.Context, dynamic.Interface, scale.ScalesGetter, ScalableResource, *unstructured.UnstructuredList, bool) ([]ScaleInfo, error)
+}
+
+var downscaler = namespaceScaler{
+	scaledObjects: DownscaleScaledObjects,
+	deployments:   DownscaleDeployments,
+	statefulsets:  DownscaleStatefulSets,
+	daemonsets:    DownscaleDaemonsets,
+	cronjobs:      DownscaleCronJobs,
+	jobs:          DownscaleJobs,
+	hpas:          DownscaleHorizontalPodAutoscalers,
+	scalables:     DownscaleScalableObjects,
+}
+
+var upscaler = namespaceScaler{
+	scaledObjects: UpscaleScaledObjects,
+	deployments:   UpscaleDeployments,
+	statefulsets:  UpscaleStatefulSets,
+	daemonsets:    UpscaleDaemonsets,
+	cronjobs:      UpscaleCronJobs,
+	jobs:          UpscaleJobs,
+	hpas:          UpscaleHorizontalPodAutoscalers,
+	scalables:     UpscaleScalableObjects,
+}
+
+// scaleNamespace downscales or upscales the resources of a namespace selected by the features and list options of
+// the controller, in the same order as the down and up commands
+func (c *Controller) scaleNamespace(ctx context.Context, namespace string, down bool, now time.Time) (NamespaceResult, error) {
+	result := NamespaceResult{Namespace: namespace}
+	scaler := upscaler
+	if down {
+		scaler = downscaler
+	}
+	features := c.Features
+
+	deployments := &v1.DeploymentList{}
+	statefulsets := &v1.StatefulSetList{}
+	scaledObjects := &unstructured.UnstructuredList{}
+	var err error
+	if features.Deployments {
+		if deployments, err = GetDeployments(ctx, c.Clientset, namespace, c.ListOptions); err != nil {
+			return result, err
+		}
+	}
+	if features.StatefulSets {
+		if statefulsets, err = GetStatefulSets(ctx, c.Clientset, namespace, c.ListOptions); err != nil {
+			return result, err
+		}
+	}
+	if features.ScaledObjects {
+		allScaledObjects, err := GetScaledObjects(ctx, c.DynamicClient, namespace)
+		if err != nil {
+			return result, err
+		}
+		scaledObjects = ScaledObjectsFor(allScaledObjects, deployments, statefulsets)
+	}
+
+	var resultError error
+	group := func(groupType string, enabled bool, scale func() ([]ScaleInfo, error)) ResourceGroup {
+		if !enabled {
+			return ResourceGroup{Type: groupType, Skipped: true}
+		}
+		infos, err := scale()
+		resultError = errors.Join(resultError, err)
+		return ResourceGroup{Type: groupType, Resources: infos}
+	}
+	result.ScaledObjects = group("ScaledObjects", features.ScaledObjects, func() ([]ScaleInfo, error) {
+		return scaler.scaledObjects(ctx, c.DynamicClient, scaledObjects, c.DryRun)
+	})
+	result.Deployments = group("Deployments", features.Deployments, func() ([]ScaleInfo, error) {
+		return scaler.deployments(ctx, c.Clientset, DeploymentsWithoutScaledObjects(deployments, scaledObjects), c.DryRun)
+	})
+	result.StatefulSets = group("StatefulSets", features.StatefulSets, func() ([]ScaleInfo, error) {
+		return scaler.statefulsets(ctx, c.Clientset, StatefulSetsWithoutScaledObjects(statefulsets, scaledObjects), c.DryRun)
+	})
+	result.DaemonSets = group("DaemonSets", features.DaemonSets, func() ([]ScaleInfo, error) {
+		daemonsets, err := GetDaemonsets(ctx, c.Clientset, namespace, c.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		return scaler.daemonsets(ctx, c.Clientset, daemonsets, c.DryRun)
+	})
+	result.CronJobs = group("CronJobs", features.CronJobs, func() ([]ScaleInfo, error) {
+		cronjobs, err := GetCronJobs(ctx, c.Clientset, namespace, c.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		return scaler.cronjobs(ctx, c.Clientset, cronjobs, c.DryRun)
+	})
+	result.Jobs = group("Jobs", features.Jobs, func() ([]ScaleInfo, error) {
+		jobs, err := GetJobs(ctx, c.Clientset, namespace, c.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		return scaler.jobs(ctx, c.Clientset, jobs, c.DryRun)
+	})
+	result.HPAs = group("HorizontalPodAutoscalers", features.HPAs, func() ([]ScaleInfo, error) {
+		hpas, err := GetHorizontalPodAutoscalers(ctx, c.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return scaler.hpas(ctx, c.Clientset, HorizontalPodAutoscalersFor(hpas, result), c.DryRun)
+	})
+	for _, resource := range features.Scalables {
+		result.Scalables = append(result.Scalables, group(resource.Name(), true, func() ([]ScaleInfo, error) {
+			objects, err := GetScalableObjects(ctx, c.DynamicClient, namespace, resource, c.ListOptions)
+			if err != nil {
+				return nil, err
+			}
+			return scaler.scalables(ctx, c.DynamicClient, c.ScaleClient, resource, objects, c.DryRun)
+		}))
+	}
+
+	if down {
+		audit := Audit{At: now.UTC().Truncate(time.Second), By: ControllerOperator, Reason: "sleep schedule"}
+		resultError = errors.Join(resultError, RecordAudit(ctx, c.DynamicClient, result, features.Scalables, audit, c.DryRun))
+	} else {
+		resultError = errors.Join(resultError, ClearAudit(ctx, c.DynamicClient, result, features.Scalables, c.DryRun))
+	}
+	return result, resultError
+}
+
+// scaledCount counts the resources scaled in a result
+func scaledCount(result NamespaceResult) int {
+	count := 0
+	groups := []ResourceGroup{result.ScaledObjects, result.Deployments, result.StatefulSets, result.DaemonSets, result.CronJobs, result.Jobs, result.HPAs}
+	for _, group := range append(groups, result.Scalables...) {
+		for _, info := range group.Resources {
+			if info.Scaled {
+				count++
+			}
+		}
+	}
+	return count
+}
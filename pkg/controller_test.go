@@ -0,0 +1,182 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestControllerReconcile(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{
+			sleepScheduleAnnotation: "Mon-Fri 20:00-07:00",
+		}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "protected", Annotations: map[string]string{
+			sleepScheduleAnnotation: "*",
+			excludeAnnotation:       "true",
+		}}},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{}},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(3)},
+		},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "protected", Annotations: map[string]string{}},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(3)},
+		},
+	)
+	dynamicClient := newSnapshotDynamicClient(newUnstructured("apps/v1", "Deployment", "web", nil))
+	clock := clocktesting.NewFakeClock(time.Date(2025, 3, 5, 15, 0, 0, 0, time.UTC)) // Wednesday afternoon
+	var logs bytes.Buffer
+	controller := &Controller{
+		Clientset:     clientset,
+		DynamicClient: dynamicClient,
+		Clock:         clock,
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
+		Interval:      time.Minute,
+		Features:      AllFeatures,
+	}
+
+	replicas := func(namespace string) int32 {
+		d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, "web", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return *d.Spec.Replicas
+	}
+	state := func() string {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, "default", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return ns.Annotations[scheduleStateAnnotation]
+	}
+
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(3), replicas("default"))
+	assert.Equal(t, scheduleStateAwake, state())
+
+	clock.SetTime(time.Date(2025, 3, 5, 21, 0, 0, 0, time.UTC))
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(0), replicas("default"))
+	assert.Equal(t, scheduleStateAsleep, state())
+	assert.Contains(t, logs.String(), `msg="Applying schedule" namespace=default state=asleep`)
+	o, err := dynamicClient.Resource(deploymentsResource).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, ControllerOperator, o.GetAnnotations()[downscaledByAnnotation])
+
+	// Bringing the namespace up by hand is left alone until the schedule changes state
+	_, err = UpscaleDeployments(ctx, clientset, &v1.DeploymentList{Items: []v1.Deployment{getDeployment(t, clientset, "default")}}, false)
+	assert.NoError(t, err)
+	clock.SetTime(time.Date(2025, 3, 5, 23, 0, 0, 0, time.UTC))
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(3), replicas("default"))
+
+	clock.SetTime(time.Date(2025, 3, 6, 7, 0, 0, 0, time.UTC))
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(3), replicas("default"))
+	assert.Equal(t, scheduleStateAwake, state())
+
+	clock.SetTime(time.Date(2025, 3, 6, 20, 0, 0, 0, time.UTC))
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(0), replicas("default"))
+
+	clock.SetTime(time.Date(2025, 3, 7, 7, 0, 0, 0, time.UTC))
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(3), replicas("default"))
+
+	assert.Equal(t, int32(3), replicas("protected"))
+}
+
+func TestControllerReconcileInDryRun(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{sleepScheduleAnnotation: "*"}}},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{}},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(3)},
+		},
+	)
+	var logs bytes.Buffer
+	controller := &Controller{
+		Clientset:     clientset,
+		DynamicClient: newSnapshotDynamicClient(),
+		Clock:         clocktesting.NewFakeClock(time.Now()),
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
+		Features:      AllFeatures,
+		DryRun:        true,
+	}
+
+	assert.NoError(t, controller.Reconcile(ctx))
+	d := getDeployment(t, clientset, "default")
+	assert.Equal(t, int32(3), *d.Spec.Replicas)
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "default", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, ns.Annotations, scheduleStateAnnotation)
+
+	// The schedule is only applied again once its state changes
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, 1, strings.Count(logs.String(), "Applying schedule"))
+}
+
+func TestControllerReconcileSelectedResources(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{sleepScheduleAnnotation: "*"}}},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"tier": "frontend"}, Annotations: map[string]string{}},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(3)},
+		},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Labels: map[string]string{"tier": "backend"}, Annotations: map[string]string{}},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(2)},
+		},
+		&v1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"tier": "backend"}, Annotations: map[string]string{}},
+			Spec:       v1.StatefulSetSpec{Replicas: int32Ptr(1)},
+		},
+	)
+	controller := &Controller{
+		Clientset:     clientset,
+		DynamicClient: newSnapshotDynamicClient(newUnstructured("apps/v1", "Deployment", "api", nil)),
+		Clock:         clocktesting.NewFakeClock(time.Now()),
+		Logger:        slog.New(slog.DiscardHandler),
+		Features:      Features{Deployments: true},
+		ListOptions:   metav1.ListOptions{LabelSelector: "tier=backend"},
+	}
+
+	assert.NoError(t, controller.Reconcile(ctx))
+	assert.Equal(t, int32(3), *getDeployment(t, clientset, "default").Spec.Replicas)
+	api, err := clientset.AppsV1().Deployments("default").Get(ctx, "api", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *api.Spec.Replicas)
+	db, err := clientset.AppsV1().StatefulSets("default").Get(ctx, "db", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *db.Spec.Replicas)
+}
+
+func TestControllerReconcileWithInvalidSchedule(t *testing.T) {
+	clientset := testclient.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{sleepScheduleAnnotation: "whenever"}}},
+	)
+	controller := &Controller{
+		Clientset:     clientset,
+		DynamicClient: newSnapshotDynamicClient(),
+		Clock:         clocktesting.NewFakeClock(time.Now()),
+		Logger:        slog.New(slog.DiscardHandler),
+	}
+
+	assert.Error(t, controller.Reconcile(context.Background()))
+}
+
+func getDeployment(t *testing.T, clientset *testclient.Clientset, namespace string) v1.Deployment {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	return *d
+}
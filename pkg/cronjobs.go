@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+func GetCronJobs(ctx context.Context, clientset kubernetes.Interface, namespace string, listOptions metav1.ListOptions) (*batchv1.CronJobList, error) {
+	cronjobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cronjobs: %w", err)
+	}
+	return cronjobs, nil
+}
+
+func DownscaleCronJobs(ctx context.Context, clientset kubernetes.Interface, cronjobs *batchv1.CronJobList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, c := range cronjobs.Items {
+		if IsExcluded(&c) {
+			results = append(results, excludedInfo(c.Name))
+			continue
+		}
+		suspended, err := suspendCronJob(ctx, clientset, c.Namespace, c.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error suspending cronjob %s: %w", c.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     c.Name,
+			Replicas: 0, // CronJobs don't have replicas
+			Scaled:   suspended,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !suspended {
+			info.Warning = "already suspended"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func UpscaleCronJobs(ctx context.Context, clientset kubernetes.Interface, cronjobs *batchv1.CronJobList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, c := range cronjobs.Items {
+		if IsExcluded(&c) {
+			results = append(results, excludedInfo(c.Name))
+			continue
+		}
+		resumed, err := resumeCronJob(ctx, clientset, c.Namespace, c.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error resuming cronjob %s: %w", c.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     c.Name,
+			Replicas: 0, // CronJobs don't have replicas
+			Scaled:   resumed,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !resumed {
+			info.Warning = "not suspended by szero"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func isCronJobSuspended(c *batchv1.CronJob) bool {
+	return c.Spec.Suspend != nil && *c.Spec.Suspend
+}
+
+func suspendCronJob(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		c, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		_, downscaled := c.Annotations[suspendAnnotation]
+		if !downscaled || !isCronJobSuspended(c) {
+			if dryRun {
+				w = true
+				return nil
+			}
+			if !downscaled {
+				if c.Annotations == nil {
+					c.Annotations = make(map[string]string)
+				}
+				c.Annotations[suspendAnnotation] = strconv.FormatBool(isCronJobSuspended(c))
+			}
+			c.Spec.Suspend = boolPtr(true)
+			_, err := clientset.BatchV1().CronJobs(c.Namespace).Update(ctx, c, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
+
+func resumeCronJob(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		c, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		originalSuspend, downscaled := c.Annotations[suspendAnnotation]
+		if downscaled {
+			suspend, err := strconv.ParseBool(originalSuspend)
+			if err != nil {
+				return fmt.Errorf("error converting suspend to bool: %w", err)
+			}
+			if dryRun {
+				w = true
+				return nil
+			}
+			c.Spec.Suspend = &suspend
+			delete(c.Annotations, suspendAnnotation)
+			_, err = clientset.BatchV1().CronJobs(c.Namespace).Update(ctx, c, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
@@ -0,0 +1,217 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetCronJobs(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+	cronjobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, cronjobs.Items, 0)
+
+	cronjob := batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}
+	_, err = clientset.BatchV1().CronJobs("default").Create(ctx, &cronjob, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	newCronJobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, newCronJobs.Items, 1)
+}
+
+func TestDownscaleCronJobs(t *testing.T) {
+	testCases := []struct {
+		name               string
+		cronjob            batchv1.CronJob
+		expectedDownscaled int
+		expectedOldSuspend string
+	}{
+		{
+			name: "When the cronjob was not previously suspended then it is suspended",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(false),
+				},
+			},
+			expectedDownscaled: 1,
+			expectedOldSuspend: "false",
+		},
+		{
+			name: "When the cronjob was suspended by the user then the original value is recorded",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedDownscaled: 1,
+			expectedOldSuspend: "true",
+		},
+		{
+			name: "When the cronjob was previously suspended by szero then nothing happens",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "false",
+					},
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedDownscaled: 0,
+			expectedOldSuspend: "false",
+		},
+		{
+			name: "When the cronjob has the suspend annotation but is not suspended then it gets suspended",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "false",
+					},
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(false),
+				},
+			},
+			expectedDownscaled: 1,
+			expectedOldSuspend: "false",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.BatchV1().CronJobs("default").Create(ctx, &tc.cronjob, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			cronjobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			downscaledInfos, err := DownscaleCronJobs(ctx, clientset, cronjobs, false)
+			assert.NoError(t, err)
+			scaledCount := countScaled(downscaledInfos)
+			assert.Equal(t, tc.expectedDownscaled, scaledCount)
+
+			newCronJobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			for _, c := range newCronJobs.Items {
+				assert.True(t, *c.Spec.Suspend)
+				oldSuspend, downscaled := c.Annotations[suspendAnnotation]
+				assert.True(t, downscaled)
+				assert.Equal(t, tc.expectedOldSuspend, oldSuspend)
+			}
+		})
+	}
+}
+
+func TestUpscaleCronJobs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		cronjob          batchv1.CronJob
+		expectedUpscaled int
+		expectedSuspend  bool
+	}{
+		{
+			name: "When the cronjob was previously suspended by szero then it is resumed",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "false",
+					},
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedUpscaled: 1,
+			expectedSuspend:  false,
+		},
+		{
+			name: "When the cronjob was suspended by the user before szero then it stays suspended",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "true",
+					},
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedUpscaled: 1,
+			expectedSuspend:  true,
+		},
+		{
+			name: "When the cronjob was not suspended by szero then nothing happens",
+			cronjob: batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.CronJobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedUpscaled: 0,
+			expectedSuspend:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.BatchV1().CronJobs("default").Create(ctx, &tc.cronjob, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			cronjobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			upscaledInfos, err := UpscaleCronJobs(ctx, clientset, cronjobs, false)
+			assert.NoError(t, err)
+			scaledCount := countScaled(upscaledInfos)
+			assert.Equal(t, tc.expectedUpscaled, scaledCount)
+
+			newCronJobs, err := GetCronJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			for _, c := range newCronJobs.Items {
+				assert.Equal(t, tc.expectedSuspend, *c.Spec.Suspend)
+				_, present := c.Annotations[suspendAnnotation]
+				assert.False(t, present)
+			}
+		})
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
 )
 
-func GetDaemonsets(ctx context.Context, clientset kubernetes.Interface, namespace string) (*v1.DaemonSetList, error) {
-	daemonsets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+func GetDaemonsets(ctx context.Context, clientset kubernetes.Interface, namespace string, listOptions metav1.ListOptions) (*v1.DaemonSetList, error) {
+	daemonsets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -24,6 +25,10 @@ func DownscaleDaemonsets(ctx context.Context, clientset kubernetes.Interface, da
 	var resultError error
 	var results []ScaleInfo
 	for _, d := range daemonsets.Items {
+		if IsExcluded(&d) {
+			results = append(results, excludedInfo(d.Name))
+			continue
+		}
 		downscaled, err := downscaleDaemonset(ctx, clientset, d.Namespace, d.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling down resource %s: %w", d.GetName(), err), resultError)
@@ -33,7 +38,9 @@ func DownscaleDaemonsets(ctx context.Context, clientset kubernetes.Interface, da
 			Replicas: 0, // DaemonSets don't have replicas
 			Scaled:   downscaled,
 		}
-		if !downscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !downscaled {
 			info.Warning = "already downscaled"
 		}
 		results = append(results, info)
@@ -45,6 +52,10 @@ func UpscaleDaemonsets(ctx context.Context, clientset kubernetes.Interface, daem
 	var resultError error
 	var results []ScaleInfo
 	for _, d := range daemonsets.Items {
+		if IsExcluded(&d) {
+			results = append(results, excludedInfo(d.Name))
+			continue
+		}
 		upscaled, err := upscaleDaemonset(ctx, clientset, d.Namespace, d.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling up resource %s: %w", d.GetName(), err), resultError)
@@ -54,7 +65,9 @@ func UpscaleDaemonsets(ctx context.Context, clientset kubernetes.Interface, daem
 			Replicas: 0, // DaemonSets don't have replicas
 			Scaled:   upscaled,
 		}
-		if !upscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !upscaled {
 			info.Warning = "already scaled up"
 		}
 		results = append(results, info)
@@ -121,31 +134,34 @@ func IsDaemonSetReady(ds *v1.DaemonSet, downscaled bool) bool {
 	return ds.Status.NumberReady == ds.Status.DesiredNumberScheduled
 }
 
-func WaitForDaemonSets(ctx context.Context, clientset kubernetes.Interface, daemonsets *v1.DaemonSetList, timeout time.Duration, downscaled bool) error {
-	ticker := time.NewTicker(1 * time.Second)
-	timeoutAfter := time.After(timeout)
-
-	for {
-		select {
-		case <-timeoutAfter:
-			return fmt.Errorf("timeout waiting for DaemonSets to reconcile")
-		case <-ticker.C:
-			done := true
-			for _, d := range daemonsets.Items {
-				ds, err := clientset.AppsV1().DaemonSets(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
-				if err != nil {
-					return fmt.Errorf("error getting DaemonSet %s: %w", d.Name, err)
-				}
-				if IsDaemonSetReady(ds, downscaled) {
-					continue
-				} else {
-					done = false
-					break
-				}
-			}
-			if done {
-				return nil
-			}
+// WaitForDaemonSets waits until the daemonsets that are not excluded reach the desired state, watching them for changes
+func WaitForDaemonSets(ctx context.Context, clientset kubernetes.Interface, daemonsets *v1.DaemonSetList, options WaitOptions) error {
+	var waited []*v1.DaemonSet
+	namespace := ""
+	for i := range daemonsets.Items {
+		if !IsExcluded(&daemonsets.Items[i]) {
+			waited = append(waited, &daemonsets.Items[i])
+			namespace = daemonsets.Items[i].Namespace
 		}
 	}
+
+	return waitUntilReady(ctx, clientset, namespace, waitedKind[*v1.DaemonSet]{
+		resource: "daemonsets",
+		kind:     "DaemonSet",
+		objType:  &v1.DaemonSet{},
+		list: func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
+		},
+		watch: func(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().DaemonSets(namespace).Watch(ctx, listOptions)
+		},
+		ready: func(d *v1.DaemonSet) bool { return IsDaemonSetReady(d, options.Downscaled) },
+		replicas: func(d *v1.DaemonSet) (int32, int32) {
+			if options.Downscaled {
+				return d.Status.NumberReady, 0
+			}
+			return d.Status.NumberReady, d.Status.DesiredNumberScheduled
+		},
+		selector: func(d *v1.DaemonSet) *metav1.LabelSelector { return d.Spec.Selector },
+	}, waited, options)
 }
@@ -14,7 +14,7 @@ import (
 func TestGetDaemonSets(t *testing.T) {
 	ctx := context.Background()
 	clientset := testclient.NewClientset()
-	daemonsets, err := GetDaemonsets(ctx, clientset, "default")
+	daemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, daemonsets.Items, 0)
 
@@ -27,7 +27,7 @@ func TestGetDaemonSets(t *testing.T) {
 	_, err = clientset.AppsV1().DaemonSets("default").Create(ctx, &daemonset, metav1.CreateOptions{})
 	assert.NoError(t, err)
 
-	newDaemonSets, err := GetDaemonsets(ctx, clientset, "default")
+	newDaemonSets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, newDaemonSets.Items, 1)
 }
@@ -79,7 +79,7 @@ func TestDownscaleDaemonSets(t *testing.T) {
 			_, err := clientset.AppsV1().DaemonSets("default").Create(ctx, &tc.daemonset, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			daemonsets, err := GetDaemonsets(ctx, clientset, "default")
+			daemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			downscaledInfos, err := DownscaleDaemonsets(ctx, clientset, daemonsets, false)
@@ -87,7 +87,7 @@ func TestDownscaleDaemonSets(t *testing.T) {
 			scaledCount := countScaled(downscaledInfos)
 			assert.Equal(t, tc.expectedDownscaled, scaledCount)
 
-			newDaemonsets, err := GetDaemonsets(ctx, clientset, "default")
+			newDaemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDaemonsets.Items {
@@ -146,7 +146,7 @@ func TestUpscaleDaemonSets(t *testing.T) {
 			_, err := clientset.AppsV1().DaemonSets("default").Create(ctx, &tc.daemonset, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			daemonsets, err := GetDaemonsets(ctx, clientset, "default")
+			daemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			upscaledInfos, err := UpscaleDaemonsets(ctx, clientset, daemonsets, false)
@@ -154,7 +154,7 @@ func TestUpscaleDaemonSets(t *testing.T) {
 			scaledCount := countScaled(upscaledInfos)
 			assert.Equal(t, tc.expectedUpscaled, scaledCount)
 
-			newDaemonsets, err := GetDaemonsets(ctx, clientset, "default")
+			newDaemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDaemonsets.Items {
@@ -5,10 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
 )
@@ -17,6 +18,10 @@ func UpscaleDeployments(ctx context.Context, clientset kubernetes.Interface, dep
 	var resultError error
 	var results []ScaleInfo
 	for _, d := range deployments.Items {
+		if IsExcluded(&d) {
+			results = append(results, excludedInfo(d.Name))
+			continue
+		}
 		upscaled, replicas, err := upscaleDeployment(ctx, clientset, d.Namespace, d.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling up deployment %s: %w", d.Name, err), resultError)
@@ -26,7 +31,9 @@ func UpscaleDeployments(ctx context.Context, clientset kubernetes.Interface, dep
 			Replicas: replicas,
 			Scaled:   upscaled,
 		}
-		if !upscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !upscaled {
 			info.Warning = "already scaled up"
 		}
 		results = append(results, info)
@@ -38,6 +45,10 @@ func DownscaleDeployments(ctx context.Context, clientset kubernetes.Interface, d
 	var resultError error
 	var results []ScaleInfo
 	for _, d := range deployments.Items {
+		if IsExcluded(&d) {
+			results = append(results, excludedInfo(d.Name))
+			continue
+		}
 		downscaled, originalReplicas, err := downscaleDeployment(ctx, clientset, d.Namespace, d.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling down deployment %s: %w", d.Name, err), resultError)
@@ -47,7 +58,9 @@ func DownscaleDeployments(ctx context.Context, clientset kubernetes.Interface, d
 			Replicas: originalReplicas,
 			Scaled:   downscaled,
 		}
-		if !downscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !downscaled {
 			info.Warning = "already downscaled"
 		}
 		results = append(results, info)
@@ -55,8 +68,8 @@ func DownscaleDeployments(ctx context.Context, clientset kubernetes.Interface, d
 	return results, resultError
 }
 
-func GetDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string) (*v1.DeploymentList, error) {
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+func GetDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, listOptions metav1.ListOptions) (*v1.DeploymentList, error) {
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error getting deployments: %w", err)
 	}
@@ -132,31 +145,34 @@ func upscaleDeployment(ctx context.Context, clientset kubernetes.Interface, name
 	return w, targetReplicas, err
 }
 
-func WaitForDeployments(ctx context.Context, clientset kubernetes.Interface, deployments *v1.DeploymentList, timeout time.Duration, downscaled bool) error {
-	ticker := time.NewTicker(1 * time.Second)
-	timeoutAfter := time.After(timeout)
-
-	for {
-		select {
-		case <-timeoutAfter:
-			return fmt.Errorf("timeout waiting for deployments to reconcile")
-		case <-ticker.C:
-			done := true
-			for _, d := range deployments.Items {
-				dp, err := clientset.AppsV1().Deployments(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
-				if err != nil {
-					return fmt.Errorf("error getting deployment %s: %w", d.Name, err)
-				}
-				if IsDeploymentReady(dp, downscaled) {
-					continue
-				} else {
-					done = false
-					break
-				}
-			}
-			if done {
-				return nil
-			}
+// WaitForDeployments waits until the deployments that are not excluded reach the desired state, watching them for changes
+func WaitForDeployments(ctx context.Context, clientset kubernetes.Interface, deployments *v1.DeploymentList, options WaitOptions) error {
+	var waited []*v1.Deployment
+	namespace := ""
+	for i := range deployments.Items {
+		if !IsExcluded(&deployments.Items[i]) {
+			waited = append(waited, &deployments.Items[i])
+			namespace = deployments.Items[i].Namespace
 		}
 	}
+
+	return waitUntilReady(ctx, clientset, namespace, waitedKind[*v1.Deployment]{
+		resource: "deployments",
+		kind:     "Deployment",
+		objType:  &v1.Deployment{},
+		list: func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		},
+		watch: func(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().Deployments(namespace).Watch(ctx, listOptions)
+		},
+		ready: func(d *v1.Deployment) bool { return IsDeploymentReady(d, options.Downscaled) },
+		replicas: func(d *v1.Deployment) (int32, int32) {
+			if options.Downscaled {
+				return d.Status.Replicas, 0
+			}
+			return d.Status.AvailableReplicas, *d.Spec.Replicas
+		},
+		selector: func(d *v1.Deployment) *metav1.LabelSelector { return d.Spec.Selector },
+	}, waited, options)
 }
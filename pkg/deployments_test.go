@@ -2,18 +2,21 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestGetDeployments(t *testing.T) {
 	ctx := context.Background()
 	clientset := testclient.NewClientset()
-	deployments, err := GetDeployments(ctx, clientset, "default")
+	deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, deployments.Items, 0)
 
@@ -26,11 +29,33 @@ func TestGetDeployments(t *testing.T) {
 	_, err = clientset.AppsV1().Deployments("default").Create(ctx, &deployment, metav1.CreateOptions{})
 	assert.NoError(t, err)
 
-	newDeployments, err := GetDeployments(ctx, clientset, "default")
+	newDeployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, newDeployments.Items, 1)
 }
 
+func TestGetDeploymentsWithSelector(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+
+	for name, tier := range map[string]string{"backend": "backend", "ingress": "frontend"} {
+		deployment := v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"tier": tier},
+			},
+		}
+		_, err := clientset.AppsV1().Deployments("default").Create(ctx, &deployment, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{LabelSelector: "tier=backend"})
+	assert.NoError(t, err)
+	assert.Len(t, deployments.Items, 1)
+	assert.Equal(t, "backend", deployments.Items[0].Name)
+}
+
 func TestDownscaleDeployments(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -101,7 +126,7 @@ func TestDownscaleDeployments(t *testing.T) {
 			_, err := clientset.AppsV1().Deployments("default").Create(ctx, &tc.deployment, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			deployments, err := GetDeployments(ctx, clientset, "default")
+			deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			downscaledInfos, err := DownscaleDeployments(ctx, clientset, deployments, false)
@@ -109,7 +134,7 @@ func TestDownscaleDeployments(t *testing.T) {
 			scaledCount := countScaled(downscaledInfos)
 			assert.Equal(t, tc.expectedDownscaled, scaledCount)
 
-			newDeployments, err := GetDeployments(ctx, clientset, "default")
+			newDeployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDeployments.Items {
@@ -170,7 +195,7 @@ func TestUpscaleDeployments(t *testing.T) {
 			_, err := clientset.AppsV1().Deployments("default").Create(ctx, &tc.deployment, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			deployments, err := GetDeployments(ctx, clientset, "default")
+			deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			upscaledInfos, err := UpscaleDeployments(ctx, clientset, deployments, false)
@@ -178,7 +203,7 @@ func TestUpscaleDeployments(t *testing.T) {
 			scaledCount := countScaled(upscaledInfos)
 			assert.Equal(t, tc.expectedUpscaled, scaledCount)
 
-			newDeployments, err := GetDeployments(ctx, clientset, "default")
+			newDeployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDeployments.Items {
@@ -189,3 +214,24 @@ func TestUpscaleDeployments(t *testing.T) {
 		})
 	}
 }
+
+func TestDownscaleDeploymentsReportsErrors(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: map[string]string{}},
+		Spec:       v1.DeploymentSpec{Replicas: int32Ptr(2)},
+	})
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("quota exceeded")
+	})
+
+	deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+
+	downscaledInfos, err := DownscaleDeployments(ctx, clientset, deployments, false)
+	assert.Error(t, err)
+	assert.Len(t, downscaledInfos, 1)
+	assert.False(t, downscaledInfos[0].Scaled)
+	assert.Equal(t, "quota exceeded", downscaledInfos[0].Error)
+	assert.Empty(t, downscaledInfos[0].Warning)
+}
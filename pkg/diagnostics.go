@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxPodProblems caps the pod problems reported for a workload, since its pods tend to have the same one
+const maxPodProblems = 5
+
+// PodProblem is why a pod is not ready, e.g. a container in CrashLoopBackOff or a pod that cannot be scheduled
+type PodProblem struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+}
+
+// GetPodProblems finds out why the pods matching a selector are not ready.
+// Pods waiting to be scheduled or started are explained by their latest warning event.
+func GetPodProblems(ctx context.Context, clientset kubernetes.Interface, namespace string, selector *metav1.LabelSelector) ([]PodProblem, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing selector: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %w", err)
+	}
+
+	var problems []PodProblem
+	// The pods waiting to be scheduled or started, by the index of their problem
+	pending := make(map[int]*corev1.Pod)
+	for i := range pods.Items {
+		if len(problems) >= maxPodProblems {
+			break
+		}
+		pod := &pods.Items[i]
+		if problem, found := podProblem(pod); found {
+			problems = append(problems, problem)
+			if problem.Container == "" && pod.Status.Phase == corev1.PodPending {
+				pending[len(problems)-1] = pod
+			}
+		}
+	}
+	for i, pod := range pending {
+		// The pod conditions tell enough without the events
+		if event, found := latestWarning(ctx, clientset, pod); found {
+			problems[i].Reason, problems[i].Message = event.Reason, event.Message
+		}
+	}
+	return problems, nil
+}
+
+// latestWarning returns the latest warning event about a pod, leaving out the events of earlier pods with the same name
+func latestWarning(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) (corev1.Event, bool) {
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod.Name,
+		"involvedObject.uid":  string(pod.UID),
+		"type":                corev1.EventTypeWarning,
+	}
+	events, err := clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector.AsSelector().String()})
+	if err != nil {
+		return corev1.Event{}, false
+	}
+	var latest *corev1.Event
+	for i, event := range events.Items {
+		if event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.Name != pod.Name || event.InvolvedObject.UID != pod.UID || event.Type != corev1.EventTypeWarning {
+			continue
+		}
+		if latest == nil || eventTime(&event).After(eventTime(latest)) {
+			latest = &events.Items[i]
+		}
+	}
+	if latest == nil {
+		return corev1.Event{}, false
+	}
+	return *latest, true
+}
+
+// podProblem tells why a pod is not ready from its status alone
+func podProblem(pod *corev1.Pod) (PodProblem, bool) {
+	if pod.DeletionTimestamp != nil {
+		return PodProblem{Pod: pod.Name, Reason: "Terminating"}, true
+	}
+	if problem, found := containerProblem(pod); found {
+		return problem, true
+	}
+	if pod.Status.Phase == corev1.PodPending {
+		problem := PodProblem{Pod: pod.Name, Reason: string(corev1.PodPending)}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+				problem.Reason, problem.Message = condition.Reason, condition.Message
+			}
+		}
+		return problem, true
+	}
+	return PodProblem{}, false
+}
+
+// containerProblem reports the first container of a pod stuck waiting for something other than starting
+func containerProblem(pod *corev1.Pod) (PodProblem, bool) {
+	statuses := slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses)
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil || waiting.Reason == "" || waiting.Reason == "ContainerCreating" || waiting.Reason == "PodInitializing" {
+			continue
+		}
+		problem := PodProblem{
+			Pod:       pod.Name,
+			Container: status.Name,
+			Image:     status.Image,
+			Reason:    waiting.Reason,
+			Message:   waiting.Message,
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil && waiting.Reason == "CrashLoopBackOff" {
+			problem.Message = fmt.Sprintf("last terminated with %s (exit code %d)", terminated.Reason, terminated.ExitCode)
+		}
+		return problem, true
+	}
+	return PodProblem{}, false
+}
+
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// WriteWaitDiagnostics writes which workloads did not reach the desired state in time and why
+func WriteWaitDiagnostics(w io.Writer, err *WaitTimeoutError) error {
+	if _, e := fmt.Fprintf(w, "Timed out waiting for %s in namespace %s:\n", err.Resource, err.Namespace); e != nil {
+		return e
+	}
+	for _, workload := range err.NotReady {
+		if _, e := fmt.Fprintf(w, "  %s %s: %d/%d replicas\n", workload.Kind, workload.Name, workload.Replicas, workload.Desired); e != nil {
+			return e
+		}
+		for _, problem := range workload.PodProblems {
+			line := fmt.Sprintf("    pod %s: %s", problem.Pod, problem.Reason)
+			if problem.Container != "" {
+				line += fmt.Sprintf(" (container %s, image %s)", problem.Container, problem.Image)
+			}
+			if problem.Message != "" {
+				line += ": " + problem.Message
+			}
+			if _, e := fmt.Fprintln(w, line); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
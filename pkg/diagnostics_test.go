@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func newProblemPod(name string, status corev1.PodStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid"), Labels: map[string]string{"app": "web"}},
+		Status:     status,
+	}
+}
+
+func newPodEvent(name string, pod string, reason string, message string, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod, Namespace: "default", UID: types.UID(pod + "-uid")},
+		Type:           corev1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestGetPodProblems(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clientset := testclient.NewClientset(
+		newProblemPod("web-running", corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "web", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+		}),
+		newProblemPod("web-crashing", corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "web",
+				Image:                "web:1.2",
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 5m0s restarting failed container"}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+			}},
+		}),
+		newProblemPod("web-starting", corev1.PodStatus{
+			Phase:             corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "web", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}},
+		}),
+		newProblemPod("web-unschedulable", corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  corev1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available",
+			}},
+		}),
+		newPodEvent("old", "web-unschedulable", "FailedScheduling", "0/3 nodes are available: 3 Insufficient memory.", now.Add(-time.Minute)),
+		newPodEvent("new", "web-unschedulable", "FailedScheduling", "0/3 nodes are available: 3 Insufficient cpu.", now),
+		newPodEvent("other", "web-crashing", "BackOff", "Back-off restarting failed container", now),
+		// An earlier pod with the same name
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "earlier", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web-unschedulable", Namespace: "default", UID: "earlier-uid"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedMount",
+			Message:        "volume not found",
+			LastTimestamp:  metav1.NewTime(now.Add(time.Minute)),
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-pending", Namespace: "default", Labels: map[string]string{"app": "api"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	)
+
+	problems, err := GetPodProblems(ctx, clientset, "default", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []PodProblem{
+		{Pod: "web-crashing", Container: "web", Image: "web:1.2", Reason: "CrashLoopBackOff", Message: "last terminated with OOMKilled (exit code 137)"},
+		{Pod: "web-starting", Reason: "Pending"},
+		{Pod: "web-unschedulable", Reason: "FailedScheduling", Message: "0/3 nodes are available: 3 Insufficient cpu."},
+	}, problems)
+}
+
+func TestWriteWaitDiagnostics(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteWaitDiagnostics(&out, &WaitTimeoutError{
+		Resource:  "deployments",
+		Namespace: "staging",
+		NotReady: []UnreadyWorkload{
+			{Kind: "Deployment", Name: "web", Replicas: 0, Desired: 2, PodProblems: []PodProblem{
+				{Pod: "web-1", Container: "web", Image: "web:1.2", Reason: "ImagePullBackOff", Message: "Back-off pulling image"},
+				{Pod: "web-2", Reason: "FailedScheduling", Message: "0/3 nodes are available"},
+			}},
+			{Kind: "Deployment", Name: "api", Replicas: 1, Desired: 3},
+		},
+	}))
+	assert.Equal(t, `Timed out waiting for deployments in namespace staging:
+  Deployment web: 0/2 replicas
+    pod web-1: ImagePullBackOff (container web, image web:1.2): Back-off pulling image
+    pod web-2: FailedScheduling: 0/3 nodes are available
+  Deployment api: 1/3 replicas
+`, out.String())
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ExcludedReason is reported for resources and namespaces opted out through the exclude annotation
+const ExcludedReason = "excluded by " + excludeAnnotation + " annotation"
+
+// IsExcluded reports whether an object opted out of szero with the exclude annotation set to true
+func IsExcluded(object metav1.Object) bool {
+	excluded, err := strconv.ParseBool(object.GetAnnotations()[excludeAnnotation])
+	return err == nil && excluded
+}
+
+// IsNamespaceExcluded reports whether a namespace opted out of szero with the exclude annotation.
+// Namespaces that do not exist are not considered excluded, while namespaces that cannot be read are an
+// error, since szero cannot tell whether they are protected.
+func IsNamespaceExcluded(ctx context.Context, clientset kubernetes.Interface, namespace string) (bool, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting namespace %s: %w", namespace, err)
+	}
+	return IsExcluded(ns), nil
+}
+
+func excludedInfo(name string) ScaleInfo {
+	return ScaleInfo{
+		Name:     name,
+		Excluded: true,
+		Warning:  ExcludedReason,
+	}
+}
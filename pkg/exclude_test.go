@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestIsExcluded(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:     "When the object has no annotations then it is not excluded",
+			expected: false,
+		},
+		{
+			name:        "When the exclude annotation is true then it is excluded",
+			annotations: map[string]string{excludeAnnotation: "true"},
+			expected:    true,
+		},
+		{
+			name:        "When the exclude annotation is false then it is not excluded",
+			annotations: map[string]string{excludeAnnotation: "false"},
+			expected:    false,
+		},
+		{
+			name:        "When the exclude annotation is not a boolean then it is not excluded",
+			annotations: map[string]string{excludeAnnotation: "maybe"},
+			expected:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsExcluded(&metav1.ObjectMeta{Annotations: tc.annotations}))
+		})
+	}
+}
+
+func TestIsNamespaceExcluded(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "protected",
+			Annotations: map[string]string{excludeAnnotation: "true"},
+		},
+	}, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+	})
+
+	excluded, err := IsNamespaceExcluded(ctx, clientset, "protected")
+	assert.NoError(t, err)
+	assert.True(t, excluded)
+
+	excluded, err = IsNamespaceExcluded(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.False(t, excluded)
+
+	excluded, err = IsNamespaceExcluded(ctx, clientset, "missing")
+	assert.NoError(t, err)
+	assert.False(t, excluded)
+
+	clientset.PrependReactor("get", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("namespaces"), action.(k8stesting.GetAction).GetName(), errors.New("not allowed"))
+	})
+	_, err = IsNamespaceExcluded(ctx, clientset, "protected")
+	assert.True(t, apierrors.IsForbidden(err))
+}
+
+func TestExcludedDeploymentsAreNotScaled(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "maintenance",
+			Namespace:   "default",
+			Annotations: map[string]string{excludeAnnotation: "true"},
+		},
+		Spec: v1.DeploymentSpec{
+			Replicas: int32Ptr(2),
+		},
+	})
+
+	deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+
+	downscaledInfos, err := DownscaleDeployments(ctx, clientset, deployments, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []ScaleInfo{{Name: "maintenance", Excluded: true, Warning: ExcludedReason}}, downscaledInfos)
+
+	d, err := clientset.AppsV1().Deployments("default").Get(ctx, "maintenance", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *d.Spec.Replicas)
+	assert.NotContains(t, d.Annotations, replicasAnnotation)
+}
+
+func TestExcludedTargetsKeepTheirAutoscalers(t *testing.T) {
+	hpas := &autoscalingv2.HorizontalPodAutoscalerList{
+		Items: []autoscalingv2.HorizontalPodAutoscaler{
+			newHorizontalPodAutoscaler("web", "Deployment", "web", nil, nil),
+			newHorizontalPodAutoscaler("maintenance", "Deployment", "maintenance", nil, nil),
+		},
+	}
+	result := NamespaceResult{
+		Deployments: ResourceGroup{Type: "Deployments", Resources: []ScaleInfo{
+			{Name: "web", Scaled: true},
+			excludedInfo("maintenance"),
+		}},
+	}
+
+	filtered := HorizontalPodAutoscalersFor(hpas, result)
+	assert.Len(t, filtered.Items, 1)
+	assert.Equal(t, "web", filtered.Items[0].Name)
+}
@@ -0,0 +1,196 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+func GetHorizontalPodAutoscalers(ctx context.Context, clientset kubernetes.Interface, namespace string) (*autoscalingv2.HorizontalPodAutoscalerList, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting horizontal pod autoscalers: %w", err)
+	}
+	return hpas, nil
+}
+
+// HorizontalPodAutoscalersFor returns the autoscalers targeting any of the deployments or statefulsets scaled in result
+func HorizontalPodAutoscalersFor(hpas *autoscalingv2.HorizontalPodAutoscalerList, result NamespaceResult) *autoscalingv2.HorizontalPodAutoscalerList {
+	targets := map[string]bool{}
+	for _, group := range []struct {
+		kind  string
+		group ResourceGroup
+	}{
+		{"Deployment", result.Deployments},
+		{"StatefulSet", result.StatefulSets},
+	} {
+		if group.group.Skipped {
+			continue
+		}
+		for _, r := range group.group.Resources {
+			if r.Excluded {
+				continue
+			}
+			targets[group.kind+"/"+r.Name] = true
+		}
+	}
+
+	filtered := hpas.DeepCopy()
+	filtered.Items = slices.DeleteFunc(filtered.Items, func(h autoscalingv2.HorizontalPodAutoscaler) bool {
+		return !targets[hpaTarget(&h)]
+	})
+	return filtered
+}
+
+func DownscaleHorizontalPodAutoscalers(ctx context.Context, clientset kubernetes.Interface, hpas *autoscalingv2.HorizontalPodAutoscalerList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, h := range hpas.Items {
+		if IsExcluded(&h) {
+			info := excludedInfo(h.Name)
+			info.Target = hpaTarget(&h)
+			results = append(results, info)
+			continue
+		}
+		parked, err := parkHorizontalPodAutoscaler(ctx, clientset, h.Namespace, h.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error parking horizontal pod autoscaler %s: %w", h.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:   h.Name,
+			Target: hpaTarget(&h),
+			Scaled: parked,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !parked {
+			info.Warning = "already parked"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func UpscaleHorizontalPodAutoscalers(ctx context.Context, clientset kubernetes.Interface, hpas *autoscalingv2.HorizontalPodAutoscalerList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, h := range hpas.Items {
+		if IsExcluded(&h) {
+			info := excludedInfo(h.Name)
+			info.Target = hpaTarget(&h)
+			results = append(results, info)
+			continue
+		}
+		unparked, err := unparkHorizontalPodAutoscaler(ctx, clientset, h.Namespace, h.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error unparking horizontal pod autoscaler %s: %w", h.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:   h.Name,
+			Target: hpaTarget(&h),
+			Scaled: unparked,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !unparked {
+			info.Warning = "not parked by szero"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func hpaTarget(h *autoscalingv2.HorizontalPodAutoscaler) string {
+	return h.Spec.ScaleTargetRef.Kind + "/" + h.Spec.ScaleTargetRef.Name
+}
+
+// isHorizontalPodAutoscalerParked reports whether scaling is disabled in both directions
+func isHorizontalPodAutoscalerParked(h *autoscalingv2.HorizontalPodAutoscaler) bool {
+	disabled := func(rules *autoscalingv2.HPAScalingRules) bool {
+		return rules != nil && rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect
+	}
+	return h.Spec.Behavior != nil && disabled(h.Spec.Behavior.ScaleUp) && disabled(h.Spec.Behavior.ScaleDown)
+}
+
+// parkHorizontalPodAutoscaler disables scaling on the autoscaler so that it doesn't fight the downscale,
+// recording its original behavior so that it can be restored when upscaling.
+func parkHorizontalPodAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		h, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		_, parked := h.Annotations[hpaBehaviorAnnotation]
+		if !parked || !isHorizontalPodAutoscalerParked(h) {
+			if dryRun {
+				w = true
+				return nil
+			}
+			if !parked {
+				behavior, err := json.Marshal(h.Spec.Behavior)
+				if err != nil {
+					return fmt.Errorf("error recording behavior: %w", err)
+				}
+				if h.Annotations == nil {
+					h.Annotations = make(map[string]string)
+				}
+				h.Annotations[hpaBehaviorAnnotation] = string(behavior)
+			}
+			if h.Spec.Behavior == nil {
+				h.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+			}
+			for _, rules := range []**autoscalingv2.HPAScalingRules{&h.Spec.Behavior.ScaleUp, &h.Spec.Behavior.ScaleDown} {
+				if *rules == nil {
+					*rules = &autoscalingv2.HPAScalingRules{}
+				}
+				disabled := autoscalingv2.DisabledPolicySelect
+				(*rules).SelectPolicy = &disabled
+			}
+			_, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(h.Namespace).Update(ctx, h, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
+
+func unparkHorizontalPodAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		h, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		originalBehavior, parked := h.Annotations[hpaBehaviorAnnotation]
+		if parked {
+			var behavior *autoscalingv2.HorizontalPodAutoscalerBehavior
+			if err := json.Unmarshal([]byte(originalBehavior), &behavior); err != nil {
+				return fmt.Errorf("error converting behavior: %w", err)
+			}
+			if dryRun {
+				w = true
+				return nil
+			}
+			h.Spec.Behavior = behavior
+			delete(h.Annotations, hpaBehaviorAnnotation)
+			_, err = clientset.AutoscalingV2().HorizontalPodAutoscalers(h.Namespace).Update(ctx, h, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func newHorizontalPodAutoscaler(name string, kind string, target string, annotations map[string]string, behavior *autoscalingv2.HorizontalPodAutoscalerBehavior) autoscalingv2.HorizontalPodAutoscaler {
+	return autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       kind,
+				Name:       target,
+			},
+			MinReplicas: int32Ptr(2),
+			MaxReplicas: 10,
+			Behavior:    behavior,
+		},
+	}
+}
+
+func TestGetHorizontalPodAutoscalers(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+	hpas, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Len(t, hpas.Items, 0)
+
+	hpa := newHorizontalPodAutoscaler("test", "Deployment", "test", nil, nil)
+	_, err = clientset.AutoscalingV2().HorizontalPodAutoscalers("default").Create(ctx, &hpa, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	newHPAs, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Len(t, newHPAs.Items, 1)
+}
+
+func TestHorizontalPodAutoscalersFor(t *testing.T) {
+	hpas := &autoscalingv2.HorizontalPodAutoscalerList{
+		Items: []autoscalingv2.HorizontalPodAutoscaler{
+			newHorizontalPodAutoscaler("web", "Deployment", "web", nil, nil),
+			newHorizontalPodAutoscaler("db", "StatefulSet", "db", nil, nil),
+			newHorizontalPodAutoscaler("other", "Deployment", "other", nil, nil),
+		},
+	}
+	result := NamespaceResult{
+		Deployments:  ResourceGroup{Type: "Deployments", Resources: []ScaleInfo{{Name: "web"}}},
+		StatefulSets: ResourceGroup{Type: "StatefulSets", Skipped: true},
+	}
+
+	filtered := HorizontalPodAutoscalersFor(hpas, result)
+	assert.Len(t, filtered.Items, 1)
+	assert.Equal(t, "web", filtered.Items[0].Name)
+	assert.Len(t, hpas.Items, 3)
+}
+
+func TestDownscaleHorizontalPodAutoscalers(t *testing.T) {
+	testCases := []struct {
+		name             string
+		hpa              autoscalingv2.HorizontalPodAutoscaler
+		expectedParked   int
+		expectedBehavior string
+	}{
+		{
+			name:             "When the autoscaler was not previously parked then it is parked",
+			hpa:              newHorizontalPodAutoscaler("test", "Deployment", "test", nil, nil),
+			expectedParked:   1,
+			expectedBehavior: "null",
+		},
+		{
+			name: "When the autoscaler has a custom behavior then it is recorded",
+			hpa: newHorizontalPodAutoscaler("test", "Deployment", "test", nil, &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: int32Ptr(60)},
+			}),
+			expectedParked:   1,
+			expectedBehavior: `{"scaleDown":{"stabilizationWindowSeconds":60}}`,
+		},
+		{
+			name: "When the autoscaler was previously parked then nothing happens",
+			hpa: newHorizontalPodAutoscaler("test", "Deployment", "test", map[string]string{hpaBehaviorAnnotation: "null"}, &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleUp:   &autoscalingv2.HPAScalingRules{SelectPolicy: new(autoscalingv2.DisabledPolicySelect)},
+				ScaleDown: &autoscalingv2.HPAScalingRules{SelectPolicy: new(autoscalingv2.DisabledPolicySelect)},
+			}),
+			expectedParked:   0,
+			expectedBehavior: "null",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("default").Create(ctx, &tc.hpa, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			hpas, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+			assert.NoError(t, err)
+
+			parkedInfos, err := DownscaleHorizontalPodAutoscalers(ctx, clientset, hpas, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedParked, countScaled(parkedInfos))
+			assert.Equal(t, "Deployment/test", parkedInfos[0].Target)
+
+			newHPAs, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+			assert.NoError(t, err)
+
+			for _, h := range newHPAs.Items {
+				assert.True(t, isHorizontalPodAutoscalerParked(&h))
+				assert.Equal(t, tc.expectedBehavior, h.Annotations[hpaBehaviorAnnotation])
+			}
+		})
+	}
+}
+
+func TestUpscaleHorizontalPodAutoscalers(t *testing.T) {
+	disabled := &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   &autoscalingv2.HPAScalingRules{SelectPolicy: new(autoscalingv2.DisabledPolicySelect)},
+		ScaleDown: &autoscalingv2.HPAScalingRules{SelectPolicy: new(autoscalingv2.DisabledPolicySelect)},
+	}
+	testCases := []struct {
+		name             string
+		hpa              autoscalingv2.HorizontalPodAutoscaler
+		expectedUnparked int
+		expectedBehavior *autoscalingv2.HorizontalPodAutoscalerBehavior
+	}{
+		{
+			name:             "When the autoscaler was parked by szero then its behavior is restored",
+			hpa:              newHorizontalPodAutoscaler("test", "Deployment", "test", map[string]string{hpaBehaviorAnnotation: `{"scaleDown":{"stabilizationWindowSeconds":60}}`}, disabled),
+			expectedUnparked: 1,
+			expectedBehavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: int32Ptr(60)},
+			},
+		},
+		{
+			name:             "When the autoscaler was parked by szero without a behavior then the behavior is removed",
+			hpa:              newHorizontalPodAutoscaler("test", "Deployment", "test", map[string]string{hpaBehaviorAnnotation: "null"}, disabled),
+			expectedUnparked: 1,
+			expectedBehavior: nil,
+		},
+		{
+			name:             "When the autoscaler was not parked by szero then nothing happens",
+			hpa:              newHorizontalPodAutoscaler("test", "Deployment", "test", nil, disabled),
+			expectedUnparked: 0,
+			expectedBehavior: disabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("default").Create(ctx, &tc.hpa, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			hpas, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+			assert.NoError(t, err)
+
+			unparkedInfos, err := UpscaleHorizontalPodAutoscalers(ctx, clientset, hpas, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedUnparked, countScaled(unparkedInfos))
+
+			newHPAs, err := GetHorizontalPodAutoscalers(ctx, clientset, "default")
+			assert.NoError(t, err)
+
+			for _, h := range newHPAs.Items {
+				assert.Equal(t, tc.expectedBehavior, h.Spec.Behavior)
+				_, present := h.Annotations[hpaBehaviorAnnotation]
+				assert.False(t, present)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func managedLabels(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/managed-by": "szero",
+	}
+}
+
+func isManaged(object metav1.Object) bool {
+	return object.GetLabels()["app.kubernetes.io/managed-by"] == "szero"
+}
+
+// inClusterRBAC builds the ServiceAccount szero runs with inside a namespace, along with its Role and RoleBinding
+func inClusterRBAC(name string, namespace string, rules []rbacv1.PolicyRule) (*corev1.ServiceAccount, *rbacv1.Role, *rbacv1.RoleBinding) {
+	meta := metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: managedLabels(name)}
+	serviceAccount := &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: meta,
+	}
+	role := &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+		ObjectMeta: *meta.DeepCopy(),
+		Rules:      rules,
+	}
+	roleBinding := &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+		ObjectMeta: *meta.DeepCopy(),
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}},
+	}
+	return serviceAccount, role, roleBinding
+}
+
+// inClusterCronJob builds a CronJob running szero with the given arguments.
+// It is excluded from szero along with its Jobs, so that downscaling its namespace never suspends them.
+func inClusterCronJob(name string, namespace string, schedule string, timeZone string, image string, serviceAccount string, args []string) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      managedLabels(name),
+			Annotations: map[string]string{excludeAnnotation: "true"},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          schedule,
+			TimeZone:          new(timeZone),
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{excludeAnnotation: "true"}},
+				Spec: batchv1.JobSpec{
+					BackoffLimit:            int32Ptr(3),
+					TTLSecondsAfterFinished: int32Ptr(3600),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: managedLabels(name)},
+						Spec: corev1.PodSpec{
+							ServiceAccountName: serviceAccount,
+							RestartPolicy:      corev1.RestartPolicyNever,
+							Containers: []corev1.Container{{
+								Name:  "szero",
+								Image: image,
+								Args:  args,
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// managedClient is the part of a typed client szero manages its in-cluster objects with
+type managedClient[T metav1.Object] interface {
+	Get(ctx context.Context, name string, options metav1.GetOptions) (T, error)
+	Create(ctx context.Context, object T, options metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, object T, options metav1.UpdateOptions) (T, error)
+	Delete(ctx context.Context, name string, options metav1.DeleteOptions) error
+}
+
+// applyManaged creates an object or updates the one szero created before, refusing to touch objects it did not create
+func applyManaged[T metav1.Object](ctx context.Context, kind string, client managedClient[T], object T) (T, error) {
+	existing, err := client.Get(ctx, object.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return client.Create(ctx, object, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return existing, err
+	}
+	if !isManaged(existing) {
+		return existing, fmt.Errorf("%s %s already exists and was not created by szero", kind, object.GetName())
+	}
+	object.SetResourceVersion(existing.GetResourceVersion())
+	return client.Update(ctx, object, metav1.UpdateOptions{})
+}
+
+// deleteManaged deletes an object szero created, leaving alone objects it did not create. It reports whether the
+// object was deleted.
+func deleteManaged[T metav1.Object](ctx context.Context, client managedClient[T], name string, options metav1.DeleteOptions) (bool, error) {
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !isManaged(existing) {
+		return false, nil
+	}
+	if err := client.Delete(ctx, name, options); err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyCronJob creates a CronJob or updates the one szero created before, refusing to touch CronJobs it did not create
+func applyCronJob(ctx context.Context, clientset kubernetes.Interface, cronJob *batchv1.CronJob) (*batchv1.CronJob, error) {
+	return applyManaged(ctx, "cronjob", clientset.BatchV1().CronJobs(cronJob.Namespace), cronJob)
+}
+
+// applyRBAC creates the ServiceAccount, Role and RoleBinding szero runs with, or updates the ones it created before.
+// It refuses to touch objects it did not create.
+func applyRBAC(ctx context.Context, clientset kubernetes.Interface, serviceAccount *corev1.ServiceAccount, role *rbacv1.Role, roleBinding *rbacv1.RoleBinding) error {
+	namespace := serviceAccount.Namespace
+	if _, err := applyManaged(ctx, "service account", clientset.CoreV1().ServiceAccounts(namespace), serviceAccount); err != nil {
+		return fmt.Errorf("error applying service account: %w", err)
+	}
+	if _, err := applyManaged(ctx, "role", clientset.RbacV1().Roles(namespace), role); err != nil {
+		return fmt.Errorf("error applying role: %w", err)
+	}
+	if _, err := applyManaged(ctx, "role binding", clientset.RbacV1().RoleBindings(namespace), roleBinding); err != nil {
+		return fmt.Errorf("error applying role binding: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+func GetJobs(ctx context.Context, clientset kubernetes.Interface, namespace string, listOptions metav1.ListOptions) (*batchv1.JobList, error) {
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error getting jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func DownscaleJobs(ctx context.Context, clientset kubernetes.Interface, jobs *batchv1.JobList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, j := range jobs.Items {
+		excluded, err := isJobExcluded(ctx, clientset, &j)
+		if err != nil {
+			resultError = errors.Join(err, resultError)
+			results = append(results, ScaleInfo{Name: j.Name, Error: err.Error()})
+			continue
+		}
+		if excluded {
+			results = append(results, excludedInfo(j.Name))
+			continue
+		}
+		suspended, warning, err := suspendJob(ctx, clientset, j.Namespace, j.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error suspending job %s: %w", j.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     j.Name,
+			Replicas: 0, // Jobs are suspended, not scaled
+			Scaled:   suspended,
+			Warning:  warning,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func UpscaleJobs(ctx context.Context, clientset kubernetes.Interface, jobs *batchv1.JobList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, j := range jobs.Items {
+		excluded, err := isJobExcluded(ctx, clientset, &j)
+		if err != nil {
+			resultError = errors.Join(err, resultError)
+			results = append(results, ScaleInfo{Name: j.Name, Error: err.Error()})
+			continue
+		}
+		if excluded {
+			results = append(results, excludedInfo(j.Name))
+			continue
+		}
+		resumed, err := resumeJob(ctx, clientset, j.Namespace, j.Name, dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error resuming job %s: %w", j.Name, err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     j.Name,
+			Replicas: 0, // Jobs are suspended, not scaled
+			Scaled:   resumed,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !resumed {
+			info.Warning = "not suspended by szero"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+// isJobExcluded reports whether a job opted out of szero, itself or through the CronJob that created it, like the
+// Jobs running szero in the cluster, which would otherwise suspend themselves
+func isJobExcluded(ctx context.Context, clientset kubernetes.Interface, j *batchv1.Job) (bool, error) {
+	if IsExcluded(j) {
+		return true, nil
+	}
+	owner := metav1.GetControllerOf(j)
+	if owner == nil || owner.Kind != "CronJob" {
+		return false, nil
+	}
+	cronJob, err := clientset.BatchV1().CronJobs(j.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting cronjob %s of job %s: %w", owner.Name, j.Name, err)
+	}
+	return IsExcluded(cronJob), nil
+}
+
+// IsJobFinished reports whether the job has reached a terminal state
+func IsJobFinished(j *batchv1.Job) bool {
+	for _, c := range j.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func isJobSuspended(j *batchv1.Job) bool {
+	return j.Spec.Suspend != nil && *j.Spec.Suspend
+}
+
+// suspendJob suspends an unfinished job, returning a warning when the job is left untouched.
+// Jobs already suspended by someone else are not annotated, so that upscaling never resumes them.
+func suspendJob(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, string, error) {
+	w := false
+	warning := ""
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		j, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		_, downscaled := j.Annotations[suspendAnnotation]
+		switch {
+		case IsJobFinished(j):
+			warning = "already finished"
+			return nil
+		case downscaled && isJobSuspended(j):
+			warning = "already suspended"
+			return nil
+		case !downscaled && isJobSuspended(j):
+			warning = "suspended by user"
+			return nil
+		}
+		if dryRun {
+			w = true
+			return nil
+		}
+		if !downscaled {
+			if j.Annotations == nil {
+				j.Annotations = make(map[string]string)
+			}
+			j.Annotations[suspendAnnotation] = strconv.FormatBool(false)
+		}
+		j.Spec.Suspend = boolPtr(true)
+		_, err = clientset.BatchV1().Jobs(j.Namespace).Update(ctx, j, metav1.UpdateOptions{})
+		if err == nil {
+			w = true
+		}
+		return err
+	})
+	return w, warning, err
+}
+
+func resumeJob(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		j, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		originalSuspend, downscaled := j.Annotations[suspendAnnotation]
+		if downscaled {
+			suspend, err := strconv.ParseBool(originalSuspend)
+			if err != nil {
+				return fmt.Errorf("error converting suspend to bool: %w", err)
+			}
+			if dryRun {
+				w = true
+				return nil
+			}
+			j.Spec.Suspend = &suspend
+			delete(j.Annotations, suspendAnnotation)
+			_, err = clientset.BatchV1().Jobs(j.Namespace).Update(ctx, j, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
@@ -0,0 +1,246 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetJobs(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+	jobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, jobs.Items, 0)
+
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}
+	_, err = clientset.BatchV1().Jobs("default").Create(ctx, &job, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	newJobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, newJobs.Items, 1)
+}
+
+func TestDownscaleJobs(t *testing.T) {
+	testCases := []struct {
+		name               string
+		job                batchv1.Job
+		expectedDownscaled int
+		expectedSuspend    bool
+		expectedAnnotated  bool
+		expectedWarning    string
+	}{
+		{
+			name: "When the job is running then it is suspended",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: boolPtr(false),
+				},
+			},
+			expectedDownscaled: 1,
+			expectedSuspend:    true,
+			expectedAnnotated:  true,
+		},
+		{
+			name: "When the job is finished then nothing happens",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			expectedDownscaled: 0,
+			expectedSuspend:    false,
+			expectedAnnotated:  false,
+			expectedWarning:    "already finished",
+		},
+		{
+			name: "When the job was suspended by the user then it is not annotated",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedDownscaled: 0,
+			expectedSuspend:    true,
+			expectedAnnotated:  false,
+			expectedWarning:    "suspended by user",
+		},
+		{
+			name: "When the job was previously suspended by szero then nothing happens",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "false",
+					},
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedDownscaled: 0,
+			expectedSuspend:    true,
+			expectedAnnotated:  true,
+			expectedWarning:    "already suspended",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.BatchV1().Jobs("default").Create(ctx, &tc.job, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			jobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			downscaledInfos, err := DownscaleJobs(ctx, clientset, jobs, false)
+			assert.NoError(t, err)
+			scaledCount := countScaled(downscaledInfos)
+			assert.Equal(t, tc.expectedDownscaled, scaledCount)
+			assert.Equal(t, tc.expectedWarning, downscaledInfos[0].Warning)
+
+			newJobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			for _, j := range newJobs.Items {
+				assert.Equal(t, tc.expectedSuspend, isJobSuspended(&j))
+				_, annotated := j.Annotations[suspendAnnotation]
+				assert.Equal(t, tc.expectedAnnotated, annotated)
+			}
+		})
+	}
+}
+
+func TestUpscaleJobs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		job              batchv1.Job
+		expectedUpscaled int
+		expectedSuspend  bool
+	}{
+		{
+			name: "When the job was previously suspended by szero then it is resumed",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					Annotations: map[string]string{
+						suspendAnnotation: "false",
+					},
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedUpscaled: 1,
+			expectedSuspend:  false,
+		},
+		{
+			name: "When the job was suspended by the user then it stays suspended",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: boolPtr(true),
+				},
+			},
+			expectedUpscaled: 0,
+			expectedSuspend:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := testclient.NewClientset()
+
+			_, err := clientset.BatchV1().Jobs("default").Create(ctx, &tc.job, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			jobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			upscaledInfos, err := UpscaleJobs(ctx, clientset, jobs, false)
+			assert.NoError(t, err)
+			scaledCount := countScaled(upscaledInfos)
+			assert.Equal(t, tc.expectedUpscaled, scaledCount)
+
+			newJobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			for _, j := range newJobs.Items {
+				assert.Equal(t, tc.expectedSuspend, isJobSuspended(&j))
+				_, present := j.Annotations[suspendAnnotation]
+				assert.False(t, present)
+			}
+		})
+	}
+}
+
+func TestDownscaleJobsSpawnedFromSchedule(t *testing.T) {
+	ctx := context.Background()
+	cronJob := inClusterCronJob(scheduleDownName, "default", "0 20 * * *", "Etc/UTC", "szero", ScheduleName, []string{"down"})
+	clientset := testclient.NewClientset(cronJob)
+	cronJob, err := clientset.BatchV1().CronJobs("default").Get(ctx, scheduleDownName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// The Job the CronJob creates, and one created before the exclude annotation made it into the job template
+	owner := []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+	for name, annotations := range map[string]map[string]string{
+		scheduleDownName + "-1": cronJob.Spec.JobTemplate.Annotations,
+		scheduleDownName + "-2": nil,
+	} {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations, OwnerReferences: owner},
+			Spec:       cronJob.Spec.JobTemplate.Spec,
+		}
+		_, err := clientset.BatchV1().Jobs("default").Create(ctx, job, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	jobs, err := GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	infos, err := DownscaleJobs(ctx, clientset, jobs, false)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+	for _, info := range infos {
+		assert.True(t, info.Excluded, info.Name)
+	}
+	jobs, err = GetJobs(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	for _, j := range jobs.Items {
+		assert.False(t, isJobSuspended(&j), j.Name)
+		assert.NotContains(t, j.Annotations, suspendAnnotation, j.Name)
+	}
+}
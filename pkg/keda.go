@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const kedaPausedReplicasAnnotation = "autoscaling.keda.sh/paused-replicas"
+
+var scaledObjectsResource = schema.GroupVersionResource{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledobjects"}
+
+// GetScaledObjects lists the KEDA ScaledObjects targeting a Deployment or StatefulSet in a namespace.
+// An empty list is returned when KEDA is not installed in the cluster.
+func GetScaledObjects(ctx context.Context, dynamicClient dynamic.Interface, namespace string) (*unstructured.UnstructuredList, error) {
+	scaledObjects, err := dynamicClient.Resource(scaledObjectsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return &unstructured.UnstructuredList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting scaledobjects: %w", err)
+	}
+	scaledObjects.Items = slices.DeleteFunc(scaledObjects.Items, func(o unstructured.Unstructured) bool {
+		kind, _ := scaledObjectTarget(&o)
+		return kind != "Deployment" && kind != "StatefulSet"
+	})
+	return scaledObjects, nil
+}
+
+// ScaledObjectsFor returns the ScaledObjects targeting any of the deployments or statefulsets that are not excluded
+func ScaledObjectsFor(scaledObjects *unstructured.UnstructuredList, deployments *v1.DeploymentList, statefulsets *v1.StatefulSetList) *unstructured.UnstructuredList {
+	targets := map[string]bool{}
+	for _, d := range deployments.Items {
+		targets["Deployment/"+d.Name] = !IsExcluded(&d)
+	}
+	for _, s := range statefulsets.Items {
+		targets["StatefulSet/"+s.Name] = !IsExcluded(&s)
+	}
+	filtered := scaledObjects.DeepCopy()
+	filtered.Items = slices.DeleteFunc(filtered.Items, func(o unstructured.Unstructured) bool {
+		kind, name := scaledObjectTarget(&o)
+		return !targets[kind+"/"+name]
+	})
+	return filtered
+}
+
+// DeploymentsWithoutScaledObjects returns the deployments that are not managed by any of the ScaledObjects
+func DeploymentsWithoutScaledObjects(deployments *v1.DeploymentList, scaledObjects *unstructured.UnstructuredList) *v1.DeploymentList {
+	targets := scaledObjectTargets(scaledObjects, "Deployment")
+	filtered := deployments.DeepCopy()
+	filtered.Items = slices.DeleteFunc(filtered.Items, func(d v1.Deployment) bool {
+		return targets[d.Name]
+	})
+	return filtered
+}
+
+// StatefulSetsWithoutScaledObjects returns the statefulsets that are not managed by any of the ScaledObjects
+func StatefulSetsWithoutScaledObjects(statefulsets *v1.StatefulSetList, scaledObjects *unstructured.UnstructuredList) *v1.StatefulSetList {
+	targets := scaledObjectTargets(scaledObjects, "StatefulSet")
+	filtered := statefulsets.DeepCopy()
+	filtered.Items = slices.DeleteFunc(filtered.Items, func(s v1.StatefulSet) bool {
+		return targets[s.Name]
+	})
+	return filtered
+}
+
+func DownscaleScaledObjects(ctx context.Context, dynamicClient dynamic.Interface, scaledObjects *unstructured.UnstructuredList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, o := range scaledObjects.Items {
+		if IsExcluded(&o) {
+			kind, name := scaledObjectTarget(&o)
+			info := excludedInfo(o.GetName())
+			info.Target = kind + "/" + name
+			results = append(results, info)
+			continue
+		}
+		paused, err := pauseScaledObject(ctx, dynamicClient, o.GetNamespace(), o.GetName(), dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error pausing scaledobject %s: %w", o.GetName(), err), resultError)
+		}
+		kind, name := scaledObjectTarget(&o)
+		info := ScaleInfo{
+			Name:   o.GetName(),
+			Target: kind + "/" + name,
+			Scaled: paused,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !paused {
+			info.Warning = "already paused"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func UpscaleScaledObjects(ctx context.Context, dynamicClient dynamic.Interface, scaledObjects *unstructured.UnstructuredList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, o := range scaledObjects.Items {
+		if IsExcluded(&o) {
+			kind, name := scaledObjectTarget(&o)
+			info := excludedInfo(o.GetName())
+			info.Target = kind + "/" + name
+			results = append(results, info)
+			continue
+		}
+		resumed, err := resumeScaledObject(ctx, dynamicClient, o.GetNamespace(), o.GetName(), dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error resuming scaledobject %s: %w", o.GetName(), err), resultError)
+		}
+		kind, name := scaledObjectTarget(&o)
+		info := ScaleInfo{
+			Name:   o.GetName(),
+			Target: kind + "/" + name,
+			Scaled: resumed,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !resumed {
+			info.Warning = "not paused by szero"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+// scaledObjectTarget returns the kind and name of the workload scaled by a ScaledObject
+func scaledObjectTarget(o *unstructured.Unstructured) (string, string) {
+	kind, _, _ := unstructured.NestedString(o.Object, "spec", "scaleTargetRef", "kind")
+	name, _, _ := unstructured.NestedString(o.Object, "spec", "scaleTargetRef", "name")
+	if kind == "" {
+		kind = "Deployment" // KEDA's default when no kind is set
+	}
+	return kind, name
+}
+
+func scaledObjectTargets(scaledObjects *unstructured.UnstructuredList, kind string) map[string]bool {
+	targets := map[string]bool{}
+	for _, o := range scaledObjects.Items {
+		if targetKind, name := scaledObjectTarget(&o); targetKind == kind {
+			targets[name] = true
+		}
+	}
+	return targets
+}
+
+// pauseScaledObject makes KEDA scale the target to 0 and keep it there, recording any paused replicas set before
+func pauseScaledObject(ctx context.Context, dynamicClient dynamic.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o, err := dynamicClient.Resource(scaledObjectsResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		annotations := o.GetAnnotations()
+		_, downscaled := annotations[kedaPausedAnnotation]
+		if !downscaled || annotations[kedaPausedReplicasAnnotation] != "0" {
+			if dryRun {
+				w = true
+				return nil
+			}
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			if !downscaled {
+				annotations[kedaPausedAnnotation] = annotations[kedaPausedReplicasAnnotation]
+			}
+			annotations[kedaPausedReplicasAnnotation] = "0"
+			o.SetAnnotations(annotations)
+			_, err := dynamicClient.Resource(scaledObjectsResource).Namespace(namespace).Update(ctx, o, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
+
+func resumeScaledObject(ctx context.Context, dynamicClient dynamic.Interface, namespace string, name string, dryRun bool) (bool, error) {
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o, err := dynamicClient.Resource(scaledObjectsResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		annotations := o.GetAnnotations()
+		originalPausedReplicas, downscaled := annotations[kedaPausedAnnotation]
+		if downscaled {
+			if dryRun {
+				w = true
+				return nil
+			}
+			if originalPausedReplicas == "" {
+				delete(annotations, kedaPausedReplicasAnnotation)
+			} else {
+				annotations[kedaPausedReplicasAnnotation] = originalPausedReplicas
+			}
+			delete(annotations, kedaPausedAnnotation)
+			o.SetAnnotations(annotations)
+			_, err := dynamicClient.Resource(scaledObjectsResource).Namespace(namespace).Update(ctx, o, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, err
+}
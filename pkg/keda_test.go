@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newScaledObject(name string, kind string, target string, annotations map[string]string) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"scaleTargetRef": map[string]any{
+				"name": target,
+			},
+		},
+	}}
+	if kind != "" {
+		_ = unstructured.SetNestedField(o.Object, kind, "spec", "scaleTargetRef", "kind")
+	}
+	o.SetAPIVersion("keda.sh/v1alpha1")
+	o.SetKind("ScaledObject")
+	o.SetNamespace("default")
+	o.SetName(name)
+	o.SetAnnotations(annotations)
+	return o
+}
+
+func newKedaDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scaledObjectsResource: "ScaledObjectList"},
+		objects...,
+	)
+}
+
+func TestGetScaledObjects(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := newKedaDynamicClient(
+		newScaledObject("default-kind", "", "web", nil),
+		newScaledObject("statefulset", "StatefulSet", "db", nil),
+		newScaledObject("rollout", "Rollout", "canary", nil),
+	)
+
+	scaledObjects, err := GetScaledObjects(ctx, dynamicClient, "default")
+	assert.NoError(t, err)
+	assert.Len(t, scaledObjects.Items, 2)
+}
+
+func TestGetScaledObjectsWithoutKeda(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient := newKedaDynamicClient()
+	dynamicClient.PrependReactor("list", scaledObjectsResource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(scaledObjectsResource.GroupResource(), "")
+	})
+
+	scaledObjects, err := GetScaledObjects(ctx, dynamicClient, "default")
+	assert.NoError(t, err)
+	assert.Len(t, scaledObjects.Items, 0)
+}
+
+func TestScaledObjectsFor(t *testing.T) {
+	scaledObjects := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+		*newScaledObject("web", "", "web", nil),
+		*newScaledObject("db", "StatefulSet", "db", nil),
+		*newScaledObject("worker", "", "worker", nil),
+	}}
+	deployments := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+	}}
+	statefulsets := &v1.StatefulSetList{Items: []v1.StatefulSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "db"}},
+	}}
+
+	filtered := ScaledObjectsFor(scaledObjects, deployments, statefulsets)
+	assert.Len(t, filtered.Items, 2)
+	assert.Equal(t, "web", filtered.Items[0].GetName())
+	assert.Equal(t, "db", filtered.Items[1].GetName())
+}
+
+func TestWorkloadsWithoutScaledObjects(t *testing.T) {
+	scaledObjects := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+		*newScaledObject("web", "", "web", nil),
+		*newScaledObject("db", "StatefulSet", "db", nil),
+	}}
+	deployments := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db"}},
+	}}
+	statefulsets := &v1.StatefulSetList{Items: []v1.StatefulSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db"}},
+	}}
+
+	filteredDeployments := DeploymentsWithoutScaledObjects(deployments, scaledObjects)
+	assert.Len(t, filteredDeployments.Items, 1)
+	assert.Equal(t, "db", filteredDeployments.Items[0].Name)
+
+	filteredStatefulSets := StatefulSetsWithoutScaledObjects(statefulsets, scaledObjects)
+	assert.Len(t, filteredStatefulSets.Items, 1)
+	assert.Equal(t, "web", filteredStatefulSets.Items[0].Name)
+}
+
+func TestDownscaleScaledObjects(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		scaledObject          *unstructured.Unstructured
+		expectedPaused        int
+		expectedOriginalPause string
+	}{
+		{
+			name:                  "When the scaledobject was not paused then it is paused at 0 replicas",
+			scaledObject:          newScaledObject("test", "", "web", nil),
+			expectedPaused:        1,
+			expectedOriginalPause: "",
+		},
+		{
+			name:                  "When the scaledobject was paused by the user then the original value is recorded",
+			scaledObject:          newScaledObject("test", "", "web", map[string]string{kedaPausedReplicasAnnotation: "2"}),
+			expectedPaused:        1,
+			expectedOriginalPause: "2",
+		},
+		{
+			name: "When the scaledobject was previously paused by szero then nothing happens",
+			scaledObject: newScaledObject("test", "", "web", map[string]string{
+				kedaPausedReplicasAnnotation: "0",
+				kedaPausedAnnotation:         "",
+			}),
+			expectedPaused:        0,
+			expectedOriginalPause: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := newKedaDynamicClient(tc.scaledObject)
+
+			scaledObjects, err := GetScaledObjects(ctx, dynamicClient, "default")
+			assert.NoError(t, err)
+
+			pausedInfos, err := DownscaleScaledObjects(ctx, dynamicClient, scaledObjects, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPaused, countScaled(pausedInfos))
+			assert.Equal(t, "Deployment/web", pausedInfos[0].Target)
+
+			o, err := dynamicClient.Resource(scaledObjectsResource).Namespace("default").Get(ctx, "test", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "0", o.GetAnnotations()[kedaPausedReplicasAnnotation])
+			originalPause, downscaled := o.GetAnnotations()[kedaPausedAnnotation]
+			assert.True(t, downscaled)
+			assert.Equal(t, tc.expectedOriginalPause, originalPause)
+		})
+	}
+}
+
+func TestUpscaleScaledObjects(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		scaledObject          *unstructured.Unstructured
+		expectedResumed       int
+		expectedPausedPresent bool
+		expectedPaused        string
+	}{
+		{
+			name: "When the scaledobject was paused by szero then it is resumed",
+			scaledObject: newScaledObject("test", "", "web", map[string]string{
+				kedaPausedReplicasAnnotation: "0",
+				kedaPausedAnnotation:         "",
+			}),
+			expectedResumed:       1,
+			expectedPausedPresent: false,
+		},
+		{
+			name: "When the scaledobject was paused by the user before szero then the user pause is restored",
+			scaledObject: newScaledObject("test", "", "web", map[string]string{
+				kedaPausedReplicasAnnotation: "0",
+				kedaPausedAnnotation:         "2",
+			}),
+			expectedResumed:       1,
+			expectedPausedPresent: true,
+			expectedPaused:        "2",
+		},
+		{
+			name:                  "When the scaledobject was not paused by szero then nothing happens",
+			scaledObject:          newScaledObject("test", "", "web", map[string]string{kedaPausedReplicasAnnotation: "0"}),
+			expectedResumed:       0,
+			expectedPausedPresent: true,
+			expectedPaused:        "0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := newKedaDynamicClient(tc.scaledObject)
+
+			scaledObjects, err := GetScaledObjects(ctx, dynamicClient, "default")
+			assert.NoError(t, err)
+
+			resumedInfos, err := UpscaleScaledObjects(ctx, dynamicClient, scaledObjects, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedResumed, countScaled(resumedInfos))
+
+			o, err := dynamicClient.Resource(scaledObjectsResource).Namespace("default").Get(ctx, "test", metav1.GetOptions{})
+			assert.NoError(t, err)
+			paused, present := o.GetAnnotations()[kedaPausedReplicasAnnotation]
+			assert.Equal(t, tc.expectedPausedPresent, present)
+			assert.Equal(t, tc.expectedPaused, paused)
+			_, downscaled := o.GetAnnotations()[kedaPausedAnnotation]
+			assert.False(t, downscaled)
+		})
+	}
+}
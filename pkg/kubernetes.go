@@ -2,23 +2,101 @@ package pkg
 
 import (
 	"fmt"
+	"os"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 const replicasAnnotation = "szero/replicas"
 const noscheduleAnnotation = "szero/noschedule"
+const suspendAnnotation = "szero/suspend"
+const hpaBehaviorAnnotation = "szero/hpa-behavior"
+const kedaPausedAnnotation = "szero/paused-replicas"
+const excludeAnnotation = "szero/exclude"
+
+// API resources of the built-in kinds, for the code working through the dynamic client
+var (
+	deploymentsResource  = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	statefulSetsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	daemonSetsResource   = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	cronJobsResource     = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	jobsResource         = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	hpasResource         = schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}
+)
+
+// AuthOverrides replace the identity and API server szero talks to, like kubectl's flags of the same names
+type AuthOverrides struct {
+	As       string   // user to impersonate
+	AsGroups []string // groups to impersonate
+	Token    string   // bearer token to authenticate with
+	Server   string   // address of the Kubernetes API server
+}
+
+// getConfig builds the client config from a kubeconfig file, or from the ServiceAccount of the pod szero runs in
+// when there is no kubeconfig file and no other API server was requested
+func getConfig(kubeconfig, context string, auth AuthOverrides) (*rest.Config, error) {
+	if !kubeconfigExists(kubeconfig) {
+		if auth.Server == "" {
+			if config, err := rest.InClusterConfig(); err == nil {
+				auth.applyTo(config)
+				return config, nil
+			}
+		} else {
+			// The API server and credentials come from the flags alone
+			kubeconfig = ""
+		}
+	}
 
-func GetClientset(kubeconfig, context string) (*kubernetes.Clientset, error) {
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
 		&clientcmd.ConfigOverrides{
 			CurrentContext: context,
+			AuthInfo: clientcmdapi.AuthInfo{
+				Impersonate:       auth.As,
+				ImpersonateGroups: auth.AsGroups,
+				Token:             auth.Token,
+			},
+			ClusterInfo: clientcmdapi.Cluster{Server: auth.Server},
 		}).ClientConfig()
 	if err != nil {
 		return nil, fmt.Errorf("error building config: %w", err)
 	}
+	return config, nil
+}
+
+// applyTo sets the overrides on a config that was not loaded from a kubeconfig file
+func (auth AuthOverrides) applyTo(config *rest.Config) {
+	if auth.As != "" || len(auth.AsGroups) > 0 {
+		config.Impersonate = rest.ImpersonationConfig{UserName: auth.As, Groups: auth.AsGroups}
+	}
+	if auth.Token != "" {
+		config.BearerToken = auth.Token
+		config.BearerTokenFile = ""
+	}
+}
+
+func kubeconfigExists(kubeconfig string) bool {
+	if kubeconfig == "" {
+		return false
+	}
+	_, err := os.Stat(kubeconfig)
+	return err == nil
+}
+
+func GetClientset(kubeconfig, context string, auth AuthOverrides) (*kubernetes.Clientset, error) {
+	config, err := getConfig(kubeconfig, context, auth)
+	if err != nil {
+		return nil, err
+	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -28,7 +106,40 @@ func GetClientset(kubeconfig, context string) (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
+func GetDynamicClient(kubeconfig, context string, auth AuthOverrides) (dynamic.Interface, error) {
+	config, err := getConfig(kubeconfig, context, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error building dynamic client: %w", err)
+	}
+
+	return client, nil
+}
+
+func GetScaleClient(kubeconfig, context string, auth AuthOverrides, discoveryClient discovery.DiscoveryInterface) (scale.ScalesGetter, error) {
+	config, err := getConfig(kubeconfig, context, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	client, err := scale.NewForConfig(config, mapper, dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+	if err != nil {
+		return nil, fmt.Errorf("error building scale client: %w", err)
+	}
+
+	return client, nil
+}
+
 func int32Ptr(i int) *int32 {
 	ptr := int32(i)
 	return &ptr
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestGetConfigWithAuthOverrides(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: user
+  user:
+    token: kubeconfig-token
+contexts:
+- name: staging
+  context:
+    cluster: cluster
+    user: user
+current-context: staging
+`), 0o600))
+
+	tests := []struct {
+		name             string
+		kubeconfig       string
+		auth             AuthOverrides
+		expectedHost     string
+		expectedToken    string
+		expectedAs       string
+		expectedAsGroups []string
+	}{
+		{
+			name:          "When there are no overrides then the kubeconfig is used as is",
+			kubeconfig:    kubeconfig,
+			expectedHost:  "https://127.0.0.1:6443",
+			expectedToken: "kubeconfig-token",
+		},
+		{
+			name:             "When impersonating then the user and groups are requested on top of the kubeconfig credentials",
+			kubeconfig:       kubeconfig,
+			auth:             AuthOverrides{As: "tenant", AsGroups: []string{"tenants", "developers"}},
+			expectedHost:     "https://127.0.0.1:6443",
+			expectedToken:    "kubeconfig-token",
+			expectedAs:       "tenant",
+			expectedAsGroups: []string{"tenants", "developers"},
+		},
+		{
+			name:          "When a token and server are given then they replace the ones of the kubeconfig",
+			kubeconfig:    kubeconfig,
+			auth:          AuthOverrides{Token: "tenant-token", Server: "https://10.0.0.1:6443"},
+			expectedHost:  "https://10.0.0.1:6443",
+			expectedToken: "tenant-token",
+		},
+		{
+			name:          "When a token and server are given without a kubeconfig then they are used alone",
+			kubeconfig:    filepath.Join(dir, "missing"),
+			auth:          AuthOverrides{Token: "tenant-token", Server: "https://10.0.0.1:6443"},
+			expectedHost:  "https://10.0.0.1:6443",
+			expectedToken: "tenant-token",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := getConfig(test.kubeconfig, "", test.auth)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedHost, config.Host)
+			assert.Equal(t, test.expectedToken, config.BearerToken)
+			assert.Equal(t, test.expectedAs, config.Impersonate.UserName)
+			assert.Equal(t, test.expectedAsGroups, config.Impersonate.Groups)
+		})
+	}
+}
+
+func TestAuthOverridesApplyTo(t *testing.T) {
+	config := &rest.Config{Host: "https://10.96.0.1:443", BearerToken: "service-account-token", BearerTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token"}
+	AuthOverrides{}.applyTo(config)
+	assert.Equal(t, "service-account-token", config.BearerToken)
+	assert.Equal(t, rest.ImpersonationConfig{}, config.Impersonate)
+
+	AuthOverrides{As: "tenant", AsGroups: []string{"tenants"}, Token: "tenant-token"}.applyTo(config)
+	assert.Equal(t, "tenant-token", config.BearerToken)
+	assert.Empty(t, config.BearerTokenFile)
+	assert.Equal(t, rest.ImpersonationConfig{UserName: "tenant", Groups: []string{"tenants"}}, config.Impersonate)
+}
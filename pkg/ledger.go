@@ -0,0 +1,248 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const ledgerConfigMapName = "szero-ledger"
+const ledgerKey = "ledger.json"
+
+// Ledger records the resources downscaled in a namespace in a ConfigMap, as a fallback for their annotations
+type Ledger struct {
+	SchemaVersion string        `json:"schemaVersion"`
+	Entries       []LedgerEntry `json:"entries"`
+
+	stored          bool   // whether the ledger was read from its ConfigMap
+	resourceVersion string // of the ConfigMap the ledger was read from, so that concurrent writers do not lose entries
+}
+
+// LedgerEntry records the state of a downscaled resource, along with who downscaled it and when
+type LedgerEntry struct {
+	ResourceSnapshot
+	Operator   string    `json:"operator,omitempty"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+func (e LedgerEntry) is(r ResourceSnapshot) bool {
+	return e.APIVersion == r.APIVersion && e.Resource == r.Resource && e.Name == r.Name
+}
+
+// Snapshot returns the ledger as the snapshot of a namespace
+func (l *Ledger) Snapshot(namespace string) NamespaceSnapshot {
+	snapshot := NamespaceSnapshot{Namespace: namespace, Resources: []ResourceSnapshot{}}
+	for _, e := range l.Entries {
+		snapshot.Resources = append(snapshot.Resources, e.ResourceSnapshot)
+	}
+	return snapshot
+}
+
+// GetLedger reads the ledger of a namespace, returning nil when there is none
+func GetLedger(ctx context.Context, clientset kubernetes.Interface, namespace string) (*Ledger, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ledgerConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting ledger: %w", err)
+	}
+	ledger := &Ledger{}
+	if err := json.Unmarshal([]byte(cm.Data[ledgerKey]), ledger); err != nil {
+		return nil, fmt.Errorf("error decoding ledger: %w", err)
+	}
+	if ledger.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported ledger schema version %q, expected %q", ledger.SchemaVersion, SchemaVersion)
+	}
+	ledger.stored = true
+	ledger.resourceVersion = cm.ResourceVersion
+	return ledger, nil
+}
+
+// RecordLedger adds the resources in a snapshot to the ledger of its namespace, creating the ledger if needed.
+// Resources already in the ledger are updated, and the ones missing from the snapshot are kept.
+func RecordLedger(ctx context.Context, clientset kubernetes.Interface, snapshot NamespaceSnapshot, operator string) error {
+	recordedAt := time.Now().UTC()
+	return updateLedger(ctx, clientset, snapshot.Namespace, func(ledger *Ledger) {
+		for _, r := range snapshot.Resources {
+			ledger.Entries = slices.DeleteFunc(ledger.Entries, func(e LedgerEntry) bool { return e.is(r) })
+			ledger.Entries = append(ledger.Entries, LedgerEntry{ResourceSnapshot: r, Operator: operator, RecordedAt: recordedAt})
+		}
+	})
+}
+
+// Select returns the entries of the ledger for the resources up brings back with the features and list options.
+// HorizontalPodAutoscalers and ScaledObjects are selected along with the Deployments and StatefulSets they target.
+func (l *Ledger) Select(ctx context.Context, dynamicClient dynamic.Interface, namespace string, features Features, listOptions metav1.ListOptions) (*Ledger, error) {
+	selected := map[schema.GroupResource]map[string]bool{}
+	targets := map[string]bool{}
+	list := func(enabled bool, resource schema.GroupVersionResource, listOptions metav1.ListOptions) ([]unstructured.Unstructured, error) {
+		if !enabled {
+			return nil, nil
+		}
+		objects, err := dynamicClient.Resource(resource).Namespace(namespace).List(ctx, listOptions)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s: %w", resource.GroupResource(), err)
+		}
+		selected[resource.GroupResource()] = map[string]bool{}
+		return objects.Items, nil
+	}
+
+	for _, resource := range []struct {
+		enabled  bool
+		resource schema.GroupVersionResource
+	}{
+		{features.Deployments, deploymentsResource},
+		{features.StatefulSets, statefulSetsResource},
+		{features.DaemonSets, daemonSetsResource},
+		{features.CronJobs, cronJobsResource},
+		{features.Jobs, jobsResource},
+	} {
+		objects, err := list(resource.enabled, resource.resource, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			selected[resource.resource.GroupResource()][o.GetName()] = true
+			targets[o.GetKind()+"/"+o.GetName()] = true
+		}
+	}
+	for _, r := range features.Scalables {
+		objects, err := list(true, r.Resource, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			selected[r.Resource.GroupResource()][o.GetName()] = true
+		}
+	}
+
+	hpas, err := list(features.HPAs, hpasResource, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range hpas {
+		kind, _, _ := unstructured.NestedString(o.Object, "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(o.Object, "spec", "scaleTargetRef", "name")
+		selected[hpasResource.GroupResource()][o.GetName()] = targets[kind+"/"+name]
+	}
+	scaledObjects, err := list(features.ScaledObjects, scaledObjectsResource, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range scaledObjects {
+		kind, name := scaledObjectTarget(&o)
+		selected[scaledObjectsResource.GroupResource()][o.GetName()] = targets[kind+"/"+name]
+	}
+
+	selection := &Ledger{SchemaVersion: l.SchemaVersion, Entries: []LedgerEntry{}, stored: l.stored, resourceVersion: l.resourceVersion}
+	for _, e := range l.Entries {
+		gv, err := schema.ParseGroupVersion(e.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing api version of %s %s: %w", e.Kind, e.Name, err)
+		}
+		if selected[gv.WithResource(e.Resource).GroupResource()][e.Name] {
+			selection.Entries = append(selection.Entries, e)
+		}
+	}
+	return selection, nil
+}
+
+// ForgetRestored removes from the ledger of a namespace the given entries whose resources no longer carry szero's
+// state, or no longer exist, deleting the ledger once it is empty. Entries recorded again in the meantime are kept.
+// It returns the number of forgotten entries.
+func ForgetRestored(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, namespace string, entries []LedgerEntry) (int, error) {
+	var restored []LedgerEntry
+	for _, e := range entries {
+		done, err := IsSnapshotRestored(ctx, dynamicClient, NamespaceSnapshot{Namespace: namespace, Resources: []ResourceSnapshot{e.ResourceSnapshot}})
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			restored = append(restored, e)
+		}
+	}
+	if len(restored) == 0 {
+		return 0, nil
+	}
+
+	forgotten := 0
+	err := updateLedger(ctx, clientset, namespace, func(ledger *Ledger) {
+		before := len(ledger.Entries)
+		ledger.Entries = slices.DeleteFunc(ledger.Entries, func(e LedgerEntry) bool {
+			return slices.ContainsFunc(restored, func(r LedgerEntry) bool {
+				return e.is(r.ResourceSnapshot) && e.RecordedAt.Equal(r.RecordedAt)
+			})
+		})
+		forgotten = before - len(ledger.Entries)
+	})
+	return forgotten, err
+}
+
+// updateLedger applies change to the current ledger of a namespace and writes it back, starting over when someone
+// else wrote the ledger in the meantime. Empty ledgers are deleted.
+func updateLedger(ctx context.Context, clientset kubernetes.Interface, namespace string, change func(ledger *Ledger)) error {
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		ledger, err := GetLedger(ctx, clientset, namespace)
+		if err != nil {
+			return err
+		}
+		if ledger == nil {
+			ledger = &Ledger{SchemaVersion: SchemaVersion}
+		}
+		change(ledger)
+
+		if len(ledger.Entries) == 0 {
+			if !ledger.stored {
+				return nil
+			}
+			err := configMaps.Delete(ctx, ledgerConfigMapName, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{ResourceVersion: &ledger.resourceVersion},
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error deleting ledger: %w", err)
+			}
+			return nil
+		}
+
+		data, err := json.MarshalIndent(ledger, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding ledger: %w", err)
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ledgerConfigMapName,
+				Namespace:       namespace,
+				Labels:          map[string]string{"app.kubernetes.io/managed-by": "szero"},
+				ResourceVersion: ledger.resourceVersion,
+			},
+			Data: map[string]string{ledgerKey: string(data)},
+		}
+		if !ledger.stored {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("error writing ledger: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestLedger(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+
+	ledger, err := GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Nil(t, ledger)
+
+	web := ResourceSnapshot{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}}
+	db := ResourceSnapshot{APIVersion: "apps/v1", Resource: "statefulsets", Kind: "StatefulSet", Name: "db", Annotations: map[string]string{replicasAnnotation: "1"}}
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{web, db}}, "alice"))
+
+	ledger, err = GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Len(t, ledger.Entries, 2)
+	assert.Equal(t, web, ledger.Entries[0].ResourceSnapshot)
+	assert.Equal(t, "alice", ledger.Entries[0].Operator)
+	assert.Equal(t, db, ledger.Entries[1].ResourceSnapshot)
+	assert.Equal(t, "alice", ledger.Entries[1].Operator)
+
+	// A later downscale updates the resources it captured, along with who downscaled them, and keeps the others
+	web.Annotations = map[string]string{replicasAnnotation: "5"}
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{web}}, "bob"))
+
+	ledger, err = GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Len(t, ledger.Entries, 2)
+	assert.Equal(t, db, ledger.Entries[0].ResourceSnapshot)
+	assert.Equal(t, "alice", ledger.Entries[0].Operator)
+	assert.Equal(t, web, ledger.Entries[1].ResourceSnapshot)
+	assert.Equal(t, "bob", ledger.Entries[1].Operator)
+	assert.Equal(t, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{db, web}}, ledger.Snapshot("default"))
+}
+
+func TestRecordLedgerRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+	web := ResourceSnapshot{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}}
+	db := ResourceSnapshot{APIVersion: "apps/v1", Resource: "statefulsets", Kind: "StatefulSet", Name: "db", Annotations: map[string]string{replicasAnnotation: "1"}}
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{web}}, "alice"))
+
+	// Someone else records db right before our first update, which then conflicts
+	conflicted := false
+	clientset.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+		data, err := json.Marshal(Ledger{SchemaVersion: SchemaVersion, Entries: []LedgerEntry{
+			{ResourceSnapshot: web, Operator: "alice"},
+			{ResourceSnapshot: db, Operator: "bob"},
+		}})
+		assert.NoError(t, err)
+		assert.NoError(t, clientset.Tracker().Update(corev1.SchemeGroupVersion.WithResource("configmaps"), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ledgerConfigMapName, Namespace: "default"},
+			Data:       map[string]string{ledgerKey: string(data)},
+		}, "default"))
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, ledgerConfigMapName, errors.New("modified"))
+	})
+	web.Annotations = map[string]string{replicasAnnotation: "5"}
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{web}}, "carol"))
+
+	ledger, err := GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{db, web}}, ledger.Snapshot("default"))
+}
+
+func TestLedgerSelect(t *testing.T) {
+	ctx := context.Background()
+	web := newUnstructured("apps/v1", "Deployment", "web", nil)
+	web.SetLabels(map[string]string{"tier": "frontend"})
+	hpa := newUnstructured("autoscaling/v2", "HorizontalPodAutoscaler", "web", nil)
+	assert.NoError(t, unstructured.SetNestedField(hpa.Object, map[string]any{"kind": "Deployment", "name": "web"}, "spec", "scaleTargetRef"))
+	dbHPA := newUnstructured("autoscaling/v2", "HorizontalPodAutoscaler", "db", nil)
+	assert.NoError(t, unstructured.SetNestedField(dbHPA.Object, map[string]any{"kind": "StatefulSet", "name": "db"}, "spec", "scaleTargetRef"))
+	dynamicClient := newSnapshotDynamicClient(
+		web,
+		newUnstructured("apps/v1", "StatefulSet", "db", nil),
+		newUnstructured("batch/v1", "CronJob", "backup", nil),
+		newRollout("canary", nil),
+		hpa,
+		dbHPA,
+	)
+	entry := func(apiVersion string, resource string, kind string, name string) LedgerEntry {
+		return LedgerEntry{ResourceSnapshot: ResourceSnapshot{APIVersion: apiVersion, Resource: resource, Kind: kind, Name: name}}
+	}
+	ledger := &Ledger{SchemaVersion: SchemaVersion, Entries: []LedgerEntry{
+		entry("apps/v1", "deployments", "Deployment", "web"),
+		entry("apps/v1", "statefulsets", "StatefulSet", "db"),
+		entry("batch/v1", "cronjobs", "CronJob", "backup"),
+		entry("argoproj.io/v1alpha1", "rollouts", "Rollout", "canary"),
+		entry("autoscaling/v2", "horizontalpodautoscalers", "HorizontalPodAutoscaler", "web"),
+		entry("autoscaling/v2", "horizontalpodautoscalers", "HorizontalPodAutoscaler", "db"),
+		entry("apps/v1", "deployments", "Deployment", "gone"),
+	}}
+
+	tests := []struct {
+		name        string
+		features    Features
+		listOptions metav1.ListOptions
+		expected    []string
+	}{
+		{
+			name:     "When nothing is filtered then the entries of the existing resources are selected",
+			features: Features{Deployments: true, StatefulSets: true, CronJobs: true, HPAs: true, Scalables: []ScalableResource{rollouts}},
+			expected: []string{"web", "db", "backup", "canary", "web", "db"},
+		},
+		{
+			name:        "When filtering by label then only the matching resources and the autoscalers targeting them are selected",
+			features:    Features{Deployments: true, StatefulSets: true, CronJobs: true, HPAs: true, Scalables: []ScalableResource{rollouts}},
+			listOptions: metav1.ListOptions{LabelSelector: "tier=frontend"},
+			expected:    []string{"web", "web"},
+		},
+		{
+			name:     "When kinds are skipped or not scaled through the scale subresource then their entries are not selected",
+			features: Features{Deployments: true, HPAs: true},
+			expected: []string{"web", "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := ledger.Select(ctx, dynamicClient, "default", tt.features, tt.listOptions)
+			assert.NoError(t, err)
+			var names []string
+			for _, e := range selection.Entries {
+				names = append(names, e.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestForgetRestored(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+	dynamicClient := newSnapshotDynamicClient(
+		newUnstructured("apps/v1", "Deployment", "web", nil),
+		newUnstructured("apps/v1", "Deployment", "api", map[string]string{replicasAnnotation: "2"}),
+		newUnstructured("apps/v1", "StatefulSet", "db", map[string]string{replicasAnnotation: "1"}),
+	)
+	web := ResourceSnapshot{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}}
+	api := ResourceSnapshot{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "api", Annotations: map[string]string{replicasAnnotation: "2"}}
+	db := ResourceSnapshot{APIVersion: "apps/v1", Resource: "statefulsets", Kind: "StatefulSet", Name: "db", Annotations: map[string]string{replicasAnnotation: "1"}}
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{web, api, db}}, "alice"))
+	ledger, err := GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+
+	// web is back up and api is still down, db was not selected
+	forgotten, err := ForgetRestored(ctx, clientset, dynamicClient, "default", ledger.Entries[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, forgotten)
+	ledger, err = GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{api, db}}, ledger.Snapshot("default"))
+
+	// Entries recorded again after being selected are kept
+	selected := ledger.Entries
+	assert.NoError(t, RecordLedger(ctx, clientset, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{api}}, "bob"))
+	dynamicClient = newSnapshotDynamicClient(
+		newUnstructured("apps/v1", "Deployment", "api", nil),
+		newUnstructured("apps/v1", "StatefulSet", "db", nil),
+	)
+	forgotten, err = ForgetRestored(ctx, clientset, dynamicClient, "default", selected)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, forgotten)
+	ledger, err = GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, NamespaceSnapshot{Namespace: "default", Resources: []ResourceSnapshot{api}}, ledger.Snapshot("default"))
+	assert.Equal(t, "bob", ledger.Entries[0].Operator)
+
+	// The ledger is deleted once every entry is forgotten
+	forgotten, err = ForgetRestored(ctx, clientset, dynamicClient, "default", ledger.Entries)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, forgotten)
+	ledger, err = GetLedger(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Nil(t, ledger)
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	clientset := testclient.NewClientset()
+	clientset.PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := &authenticationv1.SelfSubjectReview{}
+		review.Status.UserInfo.Username = "alice"
+		return true, review, nil
+	})
+
+	user, err := GetCurrentUser(context.Background(), clientset)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", user)
+}
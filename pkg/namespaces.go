@@ -2,20 +2,116 @@ package pkg
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
+// allNamespacesDenyList holds the system namespaces left out when selecting all namespaces
+var allNamespacesDenyList = []string{"kube-system", "kube-public", "kube-node-lease", "local-path-storage"}
+
+// NamespaceSelection describes which namespaces to operate on
+type NamespaceSelection struct {
+	// Patterns are literal names, globs (e.g. "preview-*") or regular expressions between slashes (e.g. "/^pr-[0-9]+$/")
+	Patterns []string
+	// Selector is a label selector namespaces must match
+	Selector string
+	// All selects every namespace except the system ones in allNamespacesDenyList
+	All bool
+}
+
+// Literal tells whether the selection is made only of literal names, which resolve to themselves without a cluster
+func (s NamespaceSelection) Literal() bool {
+	if s.Selector != "" || s.All {
+		return false
+	}
+	for _, pattern := range s.Patterns {
+		if _, literal, err := namespaceMatcher(pattern); err != nil || !literal {
+			return false
+		}
+	}
+	return true
+}
+
 func GetNamespaces(ctx context.Context, clientset kubernetes.Interface) []string {
-	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	namespaces, err := listNamespaces(ctx, clientset, metav1.ListOptions{})
 	if err != nil {
 		return []string{}
 	}
+	return namespaces
+}
+
+func listNamespaces(ctx context.Context, clientset kubernetes.Interface, listOptions metav1.ListOptions) ([]string, error) {
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error getting namespaces: %w", err)
+	}
 
 	namespaceList := make([]string, len(namespaces.Items))
 	for i, namespace := range namespaces.Items {
 		namespaceList[i] = namespace.Name
 	}
-	return namespaceList
+	return namespaceList, nil
+}
+
+// ResolveNamespaces expands a selection into namespace names.
+// Selections made only of literal names are returned as they are, without listing the cluster's namespaces.
+func ResolveNamespaces(ctx context.Context, clientset kubernetes.Interface, selection NamespaceSelection) ([]string, error) {
+	matchers := make([]func(string) bool, 0, len(selection.Patterns))
+	literalsOnly := true
+	for _, pattern := range selection.Patterns {
+		matcher, literal, err := namespaceMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+		literalsOnly = literalsOnly && literal
+	}
+	if literalsOnly && selection.Selector == "" && !selection.All {
+		return selection.Patterns, nil
+	}
+
+	available, err := listNamespaces(ctx, clientset, metav1.ListOptions{LabelSelector: selection.Selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []string
+	for _, namespace := range available {
+		if selection.All && slices.Contains(allNamespacesDenyList, namespace) {
+			continue
+		}
+		if len(matchers) > 0 && !slices.ContainsFunc(matchers, func(match func(string) bool) bool { return match(namespace) }) {
+			continue
+		}
+		resolved = append(resolved, namespace)
+	}
+	slices.Sort(resolved)
+	return resolved, nil
+}
+
+// namespaceMatcher returns a function matching namespace names against a pattern, and whether the pattern is a literal name
+func namespaceMatcher(pattern string) (func(string) bool, bool, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("^(?:" + pattern[1:len(pattern)-1] + ")$")
+		if err != nil {
+			return nil, false, fmt.Errorf("error parsing namespace pattern %s: %w", pattern, err)
+		}
+		return re.MatchString, false, nil
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, false, fmt.Errorf("error parsing namespace pattern %s: %w", pattern, err)
+		}
+		return func(namespace string) bool {
+			matched, _ := path.Match(pattern, namespace)
+			return matched
+		}, false, nil
+	}
+	return func(namespace string) bool { return namespace == pattern }, true, nil
 }
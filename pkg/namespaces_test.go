@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -31,3 +32,115 @@ func TestGetNamespaces(t *testing.T) {
 	assert.Len(t, newNamespaces, 1)
 	assert.Equal(t, "test", newNamespaces[0])
 }
+
+func TestResolveNamespaces(t *testing.T) {
+	var objects []runtime.Object
+	for name, labels := range map[string]map[string]string{
+		"default":         nil,
+		"kube-system":     nil,
+		"kube-public":     nil,
+		"preview-1":       {"env": "preview"},
+		"preview-2":       {"env": "preview"},
+		"preview-staging": {"env": "staging"},
+		"pr-42":           {"env": "preview"},
+	} {
+		objects = append(objects, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}})
+	}
+
+	testCases := []struct {
+		name      string
+		selection NamespaceSelection
+		expected  []string
+	}{
+		{
+			name:      "When only literal names are given then they are returned as they are",
+			selection: NamespaceSelection{Patterns: []string{"missing", "default"}},
+			expected:  []string{"missing", "default"},
+		},
+		{
+			name:      "When a glob is given then the matching namespaces are returned",
+			selection: NamespaceSelection{Patterns: []string{"preview-*"}},
+			expected:  []string{"preview-1", "preview-2", "preview-staging"},
+		},
+		{
+			name:      "When a regular expression is given then it must match the whole name",
+			selection: NamespaceSelection{Patterns: []string{"/preview-[0-9]+/", "/pr-.*/"}},
+			expected:  []string{"pr-42", "preview-1", "preview-2"},
+		},
+		{
+			name:      "When a namespace selector is given then only the labelled namespaces are returned",
+			selection: NamespaceSelection{Selector: "env=preview"},
+			expected:  []string{"pr-42", "preview-1", "preview-2"},
+		},
+		{
+			name:      "When a namespace selector and patterns are given then namespaces must match both",
+			selection: NamespaceSelection{Patterns: []string{"preview-*"}, Selector: "env=preview"},
+			expected:  []string{"preview-1", "preview-2"},
+		},
+		{
+			name:      "When all namespaces are requested then system namespaces are left out",
+			selection: NamespaceSelection{All: true},
+			expected:  []string{"default", "pr-42", "preview-1", "preview-2", "preview-staging"},
+		},
+		{
+			name:      "When a pattern matches nothing then no namespaces are returned",
+			selection: NamespaceSelection{Patterns: []string{"feature-*"}},
+			expected:  nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := testclient.NewClientset(objects...)
+			resolved, err := ResolveNamespaces(context.Background(), clientset, tc.selection)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+}
+
+func TestResolveNamespacesWithInvalidPattern(t *testing.T) {
+	clientset := testclient.NewClientset()
+	_, err := ResolveNamespaces(context.Background(), clientset, NamespaceSelection{Patterns: []string{"/preview-(/"}})
+	assert.Error(t, err)
+}
+
+func TestNamespaceSelectionLiteral(t *testing.T) {
+	testCases := []struct {
+		name      string
+		selection NamespaceSelection
+		expected  bool
+	}{
+		{
+			name:      "When only names are selected then the selection is literal",
+			selection: NamespaceSelection{Patterns: []string{"staging", "preview-1"}},
+			expected:  true,
+		},
+		{
+			name:      "When a glob is selected then the selection is not literal",
+			selection: NamespaceSelection{Patterns: []string{"staging", "preview-*"}},
+			expected:  false,
+		},
+		{
+			name:      "When a regular expression is selected then the selection is not literal",
+			selection: NamespaceSelection{Patterns: []string{"/^pr-[0-9]+$/"}},
+			expected:  false,
+		},
+		{
+			name:      "When a label selector is set then the selection is not literal",
+			selection: NamespaceSelection{Patterns: []string{"staging"}, Selector: "env=preview"},
+			expected:  false,
+		},
+		{
+			name:      "When all namespaces are selected then the selection is not literal",
+			selection: NamespaceSelection{All: true},
+			expected:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.selection.Literal())
+		})
+	}
+}
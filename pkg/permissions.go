@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Features selects what szero does in a namespace, and hence the permissions it needs there
+type Features struct {
+	Deployments   bool
+	StatefulSets  bool
+	DaemonSets    bool
+	CronJobs      bool
+	Jobs          bool
+	HPAs          bool
+	ScaledObjects bool
+	Scalables     []ScalableResource // resources scaled through the scale subresource
+	Wait          bool               // watch deployments, statefulsets and daemonsets until they reach the desired state
+	ReadOnly      bool               // only look at the resources, as in dry-run mode
+	Ledger        bool               // record the szero-ledger ConfigMap on down, and restore from it on up
+	WakeUp        bool               // schedule the wake-up CronJob, with the ServiceAccount, Role and RoleBinding it runs with
+}
+
+// AllFeatures scales every built-in kind
+var AllFeatures = Features{
+	Deployments:   true,
+	StatefulSets:  true,
+	DaemonSets:    true,
+	CronJobs:      true,
+	Jobs:          true,
+	HPAs:          true,
+	ScaledObjects: true,
+}
+
+// ServedFeatures turns off the optional kinds the cluster does not serve, such as KEDA ScaledObjects on clusters
+// without KEDA, so that no permissions are required for them
+func ServedFeatures(discoveryClient discovery.DiscoveryInterface, features Features) (Features, error) {
+	optional := []struct {
+		enabled  *bool
+		resource schema.GroupVersionResource
+	}{
+		{&features.HPAs, hpasResource},
+		{&features.ScaledObjects, scaledObjectsResource},
+	}
+	for _, o := range optional {
+		if !*o.enabled {
+			continue
+		}
+		served, err := isServed(discoveryClient, o.resource)
+		if err != nil {
+			return features, err
+		}
+		*o.enabled = served
+	}
+	return features, nil
+}
+
+// isServed tells whether discovery reports the resource in its group version
+func isServed(discoveryClient discovery.DiscoveryInterface, resource schema.GroupVersionResource) (bool, error) {
+	groupVersion := resource.GroupVersion().String()
+	list, err := discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error discovering resources for %s: %w", groupVersion, err)
+	}
+	return slices.ContainsFunc(list.APIResources, func(r metav1.APIResource) bool { return r.Name == resource.Resource }), nil
+}
+
+// RequiredRules returns the RBAC rules szero needs in a namespace for the selected features.
+// Resources of the same API group needing the same verbs share a rule.
+func RequiredRules(features Features) []rbacv1.PolicyRule {
+	// Listing finds the resources, getting and updating scales them, and patching records who scaled them
+	verbs := []string{"get", "list", "update", "patch"}
+	scaleVerbs := []string{"get", "update"}
+	if features.ReadOnly {
+		verbs = []string{"get", "list"}
+		scaleVerbs = []string{"get"}
+	}
+
+	// Reading the namespace tells whether it is excluded from szero
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get"}}}
+	add := func(enabled bool, group string, resource string, verbs []string) {
+		if !enabled {
+			return
+		}
+		for i, rule := range rules {
+			if rule.APIGroups[0] == group && slices.Equal(rule.Verbs, verbs) {
+				rules[i].Resources = append(rule.Resources, resource)
+				return
+			}
+		}
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{group}, Resources: []string{resource}, Verbs: slices.Clone(verbs)})
+	}
+
+	workloadVerbs := verbs
+	if features.Wait {
+		workloadVerbs = append(slices.Clone(verbs), "watch")
+	}
+
+	add(features.Deployments, deploymentsResource.Group, deploymentsResource.Resource, workloadVerbs)
+	add(features.StatefulSets, statefulSetsResource.Group, statefulSetsResource.Resource, workloadVerbs)
+	add(features.DaemonSets, daemonSetsResource.Group, daemonSetsResource.Resource, workloadVerbs)
+	add(features.CronJobs, cronJobsResource.Group, cronJobsResource.Resource, verbs)
+	add(features.Jobs, jobsResource.Group, jobsResource.Resource, verbs)
+	// Jobs spawned by an excluded CronJob are excluded as well, which takes reading the CronJob when they are not scaled
+	add(features.Jobs && !features.CronJobs, cronJobsResource.Group, cronJobsResource.Resource, []string{"get"})
+	add(features.HPAs, hpasResource.Group, hpasResource.Resource, verbs)
+	add(features.ScaledObjects, scaledObjectsResource.Group, scaledObjectsResource.Resource, verbs)
+	// Pods and their events tell why the workloads did not reach the desired state, pods are watched to fail fast
+	add(features.Wait, "", "pods", []string{"list", "watch"})
+	add(features.Wait, "", "events", []string{"list"})
+	for _, r := range features.Scalables {
+		add(true, r.Resource.Group, r.Resource.Resource, verbs)
+		add(true, r.Resource.Group, r.Resource.Resource+"/scale", scaleVerbs)
+	}
+
+	// Creating the Role of the wake-up job takes holding everything it grants, deleting its own CronJob and
+	// clearing the deadline on the namespace included
+	if features.WakeUp {
+		add(true, "batch", "cronjobs", []string{"get", "create", "update", "delete"})
+		add(true, "", "serviceaccounts", []string{"get", "create", "update"})
+		add(true, rbacv1.GroupName, "roles", []string{"get", "create", "update"})
+		add(true, rbacv1.GroupName, "rolebindings", []string{"get", "create", "update"})
+		add(true, "", "namespaces", []string{"patch"})
+	}
+	// The ledger is created on the first downscale, creating can't be limited to a name, and deleted once
+	// everything in it was brought back up
+	if features.Ledger {
+		ledgerVerbs := []string{"get"}
+		if !features.ReadOnly {
+			add(true, "", "configmaps", []string{"create"})
+			ledgerVerbs = []string{"get", "update", "delete"}
+		}
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{ledgerConfigMapName}, Verbs: ledgerVerbs})
+	}
+	return rules
+}
+
+// RBACObjects builds a Role and RoleBinding in each selected namespace, granting subject the rules.
+// Selections other than a list of names can match namespaces created later on, so they get a ClusterRole and
+// ClusterRoleBinding instead, which also allow listing the namespaces.
+func RBACObjects(name string, subject rbacv1.Subject, selection NamespaceSelection, rules []rbacv1.PolicyRule) ([]runtime.Object, error) {
+	clusterWide := selection.All || selection.Selector != ""
+	for _, pattern := range selection.Patterns {
+		_, literal, err := namespaceMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		clusterWide = clusterWide || !literal
+	}
+	roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name}
+
+	if clusterWide {
+		roleRef.Kind = "ClusterRole"
+		rules = append(slices.Clone(rules), rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"list"}})
+		return []runtime.Object{
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Rules:      rules,
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				RoleRef:    roleRef,
+				Subjects:   []rbacv1.Subject{subject},
+			},
+		}, nil
+	}
+
+	var objects []runtime.Object
+	for _, namespace := range selection.Patterns {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Rules:      rules,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				RoleRef:    roleRef,
+				Subjects:   []rbacv1.Subject{subject},
+			},
+		)
+	}
+	return objects, nil
+}
@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRequiredRules(t *testing.T) {
+	namespaceRule := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get"}}
+	tests := []struct {
+		name     string
+		features Features
+		expected []rbacv1.PolicyRule
+	}{
+		{
+			name:     "When every kind is scaled then resources of the same group share a rule",
+			features: AllFeatures,
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments", "statefulsets", "daemonsets"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"cronjobs", "jobs"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"autoscaling"}, Resources: []string{"horizontalpodautoscalers"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"keda.sh"}, Resources: []string{"scaledobjects"}, Verbs: []string{"get", "list", "update", "patch"}},
+			},
+		},
+		{
+			name:     "When kinds are skipped then they need no rules",
+			features: Features{Deployments: true, Jobs: true},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name:     "When waiting then the workloads and their pods are also watched",
+			features: Features{Deployments: true, DaemonSets: true, Jobs: true, Wait: true},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments", "daemonsets"}, Verbs: []string{"get", "list", "update", "patch", "watch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list", "watch"}},
+				{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"list"}},
+			},
+		},
+		{
+			name: "When only looking then resources and their scale subresource are only read",
+			features: Features{
+				Deployments: true,
+				Scalables:   []ScalableResource{{Resource: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}, Kind: "Rollout"}},
+				ReadOnly:    true,
+			},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{"argoproj.io"}, Resources: []string{"rollouts"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{"argoproj.io"}, Resources: []string{"rollouts/scale"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name:     "When keeping the ledger then the szero-ledger ConfigMap is created, updated and deleted",
+			features: Features{Deployments: true, Ledger: true},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{ledgerConfigMapName}, Verbs: []string{"get", "update", "delete"}},
+			},
+		},
+		{
+			name:     "When only looking at the resources then the szero-ledger ConfigMap is only read",
+			features: Features{Deployments: true, Ledger: true, ReadOnly: true},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{ledgerConfigMapName}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name:     "When scheduling a wake-up then its CronJob and permissions are created",
+			features: Features{Deployments: true, WakeUp: true},
+			expected: []rbacv1.PolicyRule{
+				namespaceRule,
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list", "update", "patch"}},
+				{APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}, Verbs: []string{"get", "create", "update", "delete"}},
+				{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, Verbs: []string{"get", "create", "update"}},
+				{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"roles", "rolebindings"}, Verbs: []string{"get", "create", "update"}},
+				{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"patch"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, RequiredRules(test.features))
+		})
+	}
+}
+
+func TestServedFeatures(t *testing.T) {
+	hpas := &metav1.APIResourceList{
+		GroupVersion: "autoscaling/v2",
+		APIResources: []metav1.APIResource{{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", Namespaced: true}},
+	}
+	scaledObjects := &metav1.APIResourceList{
+		GroupVersion: "keda.sh/v1alpha1",
+		APIResources: []metav1.APIResource{{Name: "scaledobjects", Kind: "ScaledObject", Namespaced: true}},
+	}
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		features  Features
+		expected  Features
+	}{
+		{
+			name:      "When KEDA is not installed then ScaledObjects are turned off",
+			resources: []*metav1.APIResourceList{hpas},
+			features:  AllFeatures,
+			expected:  Features{Deployments: true, StatefulSets: true, DaemonSets: true, CronJobs: true, Jobs: true, HPAs: true},
+		},
+		{
+			name:      "When no autoscaling/v2 is served then HPAs are turned off",
+			resources: []*metav1.APIResourceList{scaledObjects},
+			features:  AllFeatures,
+			expected:  Features{Deployments: true, StatefulSets: true, DaemonSets: true, CronJobs: true, Jobs: true, ScaledObjects: true},
+		},
+		{
+			name:      "When everything is served then the features are kept",
+			resources: []*metav1.APIResourceList{hpas, scaledObjects},
+			features:  AllFeatures,
+			expected:  AllFeatures,
+		},
+		{
+			name:      "When skipped kinds are served then they stay off",
+			resources: []*metav1.APIResourceList{hpas, scaledObjects},
+			features:  Features{Deployments: true},
+			expected:  Features{Deployments: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := testclient.NewClientset()
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = test.resources
+			served, err := ServedFeatures(clientset.Discovery(), test.features)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, served)
+		})
+	}
+}
+
+func TestRBACObjects(t *testing.T) {
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "szero", Namespace: "ops"}
+	rules := RequiredRules(Features{Deployments: true})
+
+	tests := []struct {
+		name          string
+		selection     NamespaceSelection
+		expectedKinds []string
+	}{
+		{
+			name:          "When namespaces are listed by name then each gets a Role and RoleBinding",
+			selection:     NamespaceSelection{Patterns: []string{"staging", "production"}},
+			expectedKinds: []string{"Role", "RoleBinding", "Role", "RoleBinding"},
+		},
+		{
+			name:          "When namespaces are matched by pattern then a ClusterRole and ClusterRoleBinding are used",
+			selection:     NamespaceSelection{Patterns: []string{"staging", "preview-*"}},
+			expectedKinds: []string{"ClusterRole", "ClusterRoleBinding"},
+		},
+		{
+			name:          "When namespaces are selected by label then a ClusterRole and ClusterRoleBinding are used",
+			selection:     NamespaceSelection{Selector: "env=preview"},
+			expectedKinds: []string{"ClusterRole", "ClusterRoleBinding"},
+		},
+		{
+			name:          "When all namespaces are selected then a ClusterRole and ClusterRoleBinding are used",
+			selection:     NamespaceSelection{All: true},
+			expectedKinds: []string{"ClusterRole", "ClusterRoleBinding"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := RBACObjects("szero", subject, test.selection, rules)
+			assert.NoError(t, err)
+			var kinds []string
+			for _, o := range objects {
+				kinds = append(kinds, o.GetObjectKind().GroupVersionKind().Kind)
+			}
+			assert.Equal(t, test.expectedKinds, kinds)
+
+			var out bytes.Buffer
+			assert.NoError(t, WriteManifests(&out, objects))
+			assert.Contains(t, out.String(), "- kind: ServiceAccount\n  name: szero\n  namespace: ops\n")
+		})
+	}
+
+	objects, err := RBACObjects("szero", subject, NamespaceSelection{All: true}, rules)
+	assert.NoError(t, err)
+	clusterRole := objects[0].(*rbacv1.ClusterRole)
+	assert.Equal(t, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"list"}}, clusterRole.Rules[len(clusterRole.Rules)-1])
+	assert.Len(t, rules, 2)
+
+	_, err = RBACObjects("szero", subject, NamespaceSelection{Patterns: []string{"/[/"}}, rules)
+	assert.Error(t, err)
+}
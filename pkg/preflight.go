@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MissingPermission is an access szero needs in a namespace and was denied
+type MissingPermission struct {
+	Namespace string `json:"namespace"`
+	Verb      string `json:"verb"`
+	Resource  string `json:"resource"` // e.g. "deployments.apps" or "rollouts.argoproj.io/scale"
+}
+
+// CheckPermissions reviews every verb of every rule in every namespace through SelfSubjectAccessReviews,
+// returning the ones the current user is denied. When there are several namespaces, each access is first
+// reviewed across all namespaces, and only reviewed namespace by namespace when that is denied.
+func CheckPermissions(ctx context.Context, clientset kubernetes.Interface, namespaces []string, rules []rbacv1.PolicyRule) ([]MissingPermission, error) {
+	var missing []MissingPermission
+	for _, attributes := range resourceAttributes(rules) {
+		if len(namespaces) > 1 {
+			allowed, err := reviewAccess(ctx, clientset, attributes)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				continue
+			}
+		}
+
+		for _, namespace := range namespaces {
+			attributes.Namespace = namespace
+			allowed, err := reviewAccess(ctx, clientset, attributes)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				missing = append(missing, MissingPermission{
+					Namespace: namespace,
+					Verb:      attributes.Verb,
+					Resource:  resourceString(attributes),
+				})
+			}
+		}
+	}
+	return missing, nil
+}
+
+// resourceAttributes expands rules into one access to review per verb and resource
+func resourceAttributes(rules []rbacv1.PolicyRule) []authorizationv1.ResourceAttributes {
+	var attributes []authorizationv1.ResourceAttributes
+	for _, rule := range rules {
+		names := rule.ResourceNames
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				resource, subresource, _ := strings.Cut(resource, "/")
+				for _, verb := range rule.Verbs {
+					for _, name := range names {
+						attributes = append(attributes, authorizationv1.ResourceAttributes{
+							Verb:        verb,
+							Group:       group,
+							Resource:    resource,
+							Subresource: subresource,
+							Name:        name,
+						})
+					}
+				}
+			}
+		}
+	}
+	return attributes
+}
+
+func reviewAccess(ctx context.Context, clientset kubernetes.Interface, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error reviewing access to %s %s: %w", attributes.Verb, resourceString(attributes), err)
+	}
+	return review.Status.Allowed, nil
+}
+
+func resourceString(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if attributes.Group != "" {
+		resource += "." + attributes.Group
+	}
+	if attributes.Subresource != "" {
+		resource += "/" + attributes.Subresource
+	}
+	if attributes.Name != "" {
+		resource += " " + attributes.Name
+	}
+	return resource
+}
+
+// WritePermissionTable writes the missing permissions as an aligned table
+func WritePermissionTable(w io.Writer, missing []MissingPermission) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAMESPACE\tVERB\tRESOURCE")
+	for _, m := range missing {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", m.Namespace, m.Verb, m.Resource)
+	}
+	return table.Flush()
+}
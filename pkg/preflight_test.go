@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newAccessReviewClientset answers SelfSubjectAccessReviews with allow, which decides on the reviewed attributes
+func newAccessReviewClientset(allow func(attributes authorizationv1.ResourceAttributes) bool) (*testclient.Clientset, *[]authorizationv1.ResourceAttributes) {
+	clientset := testclient.NewClientset()
+	var reviewed []authorizationv1.ResourceAttributes
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := *review.Spec.ResourceAttributes
+		reviewed = append(reviewed, attributes)
+		review.Status.Allowed = allow(attributes)
+		return true, review, nil
+	})
+	return clientset, &reviewed
+}
+
+func TestCheckPermissions(t *testing.T) {
+	ctx := context.Background()
+	rules := RequiredRules(Features{Deployments: true})
+
+	t.Run("When everything is allowed then nothing is missing", func(t *testing.T) {
+		clientset, reviewed := newAccessReviewClientset(func(authorizationv1.ResourceAttributes) bool { return true })
+		missing, err := CheckPermissions(ctx, clientset, []string{"staging"}, rules)
+		assert.NoError(t, err)
+		assert.Empty(t, missing)
+		assert.Len(t, *reviewed, 5)
+	})
+
+	t.Run("When access is granted across all namespaces then namespaces are not reviewed one by one", func(t *testing.T) {
+		clientset, reviewed := newAccessReviewClientset(func(authorizationv1.ResourceAttributes) bool { return true })
+		missing, err := CheckPermissions(ctx, clientset, []string{"staging", "production"}, rules)
+		assert.NoError(t, err)
+		assert.Empty(t, missing)
+		assert.Len(t, *reviewed, 5)
+		for _, attributes := range *reviewed {
+			assert.Empty(t, attributes.Namespace)
+		}
+	})
+
+	t.Run("When some access is denied then it is reported for each namespace", func(t *testing.T) {
+		clientset, _ := newAccessReviewClientset(func(attributes authorizationv1.ResourceAttributes) bool {
+			return attributes.Verb != "update" && (attributes.Verb != "patch" || attributes.Namespace == "staging")
+		})
+		missing, err := CheckPermissions(ctx, clientset, []string{"staging", "production"}, rules)
+		assert.NoError(t, err)
+		assert.Equal(t, []MissingPermission{
+			{Namespace: "staging", Verb: "update", Resource: "deployments.apps"},
+			{Namespace: "production", Verb: "update", Resource: "deployments.apps"},
+			{Namespace: "production", Verb: "patch", Resource: "deployments.apps"},
+		}, missing)
+
+		var out bytes.Buffer
+		assert.NoError(t, WritePermissionTable(&out, missing))
+		assert.Equal(t, `NAMESPACE   VERB    RESOURCE
+staging     update  deployments.apps
+production  update  deployments.apps
+production  patch   deployments.apps
+`, out.String())
+	})
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SchemaVersion is the version of the structured output, bumped on incompatible changes
+const SchemaVersion = "szero/v1"
+
+// Printer outputs the scaling results of each namespace
+type Printer interface {
+	PrintNamespaceResult(result NamespaceResult) error
+	// Flush writes any output held back until all namespaces are processed
+	Flush() error
+}
+
+// Report is the document written by the structured printers
+type Report struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Operation     string            `json:"operation"` // "down" or "up"
+	DryRun        bool              `json:"dryRun"`
+	Namespaces    []NamespaceResult `json:"namespaces"`
+}
+
+// StructuredPrinter collects the results of every namespace and writes them as a single JSON or YAML document on Flush
+type StructuredPrinter struct {
+	writer io.Writer
+	format string
+	report Report
+}
+
+// NewPrinter creates a printer for the given output format: "tree" (the default), "json" or "yaml"
+func NewPrinter(format string, operation string, dryRun bool) (Printer, error) {
+	switch format {
+	case "", "tree":
+		return NewTreePrinter(), nil
+	case "json", "yaml":
+		return NewStructuredPrinterWithWriter(os.Stdout, format, operation, dryRun), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected one of tree, json, yaml", format)
+	}
+}
+
+// NewStructuredPrinterWithWriter creates a StructuredPrinter with a custom writer
+func NewStructuredPrinterWithWriter(w io.Writer, format string, operation string, dryRun bool) *StructuredPrinter {
+	return &StructuredPrinter{
+		writer: w,
+		format: format,
+		report: Report{
+			SchemaVersion: SchemaVersion,
+			Operation:     operation,
+			DryRun:        dryRun,
+			Namespaces:    []NamespaceResult{},
+		},
+	}
+}
+
+func (sp *StructuredPrinter) PrintNamespaceResult(result NamespaceResult) error {
+	sp.report.Namespaces = append(sp.report.Namespaces, normalizeNamespaceResult(result))
+	return nil
+}
+
+func (sp *StructuredPrinter) Flush() error {
+	return WriteStructured(sp.writer, sp.format, sp.report)
+}
+
+// WriteStructured writes v as an indented JSON document, or as YAML when format is "yaml"
+func WriteStructured(w io.Writer, format string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding results: %w", err)
+	}
+	if format == "yaml" {
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("error encoding results: %w", err)
+		}
+	} else {
+		data = append(data, '\n')
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// normalizeNamespaceResult replaces missing lists with empty ones, so that consumers always get arrays
+func normalizeNamespaceResult(result NamespaceResult) NamespaceResult {
+	normalize := func(group ResourceGroup) ResourceGroup {
+		if group.Resources == nil {
+			group.Resources = []ScaleInfo{}
+		}
+		return group
+	}
+	result.Deployments = normalize(result.Deployments)
+	result.StatefulSets = normalize(result.StatefulSets)
+	result.DaemonSets = normalize(result.DaemonSets)
+	result.CronJobs = normalize(result.CronJobs)
+	result.Jobs = normalize(result.Jobs)
+	result.HPAs = normalize(result.HPAs)
+	result.ScaledObjects = normalize(result.ScaledObjects)
+	scalables := make([]ResourceGroup, 0, len(result.Scalables))
+	for _, group := range result.Scalables {
+		scalables = append(scalables, normalize(group))
+	}
+	result.Scalables = scalables
+	return result
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPrinter(t *testing.T) {
+	for _, format := range []string{"", "tree", "json", "yaml"} {
+		_, err := NewPrinter(format, "down", false)
+		assert.NoError(t, err)
+	}
+	_, err := NewPrinter("xml", "down", false)
+	assert.Error(t, err)
+}
+
+func TestStructuredPrinterJSON(t *testing.T) {
+	var out bytes.Buffer
+	printer := NewStructuredPrinterWithWriter(&out, "json", "down", true)
+	err := printer.PrintNamespaceResult(NamespaceResult{
+		Namespace: "default",
+		Deployments: ResourceGroup{Type: "Deployments", Resources: []ScaleInfo{
+			{Name: "web", Replicas: 2, Scaled: true},
+			{Name: "api", Error: "conflict"},
+		}},
+		StatefulSets: ResourceGroup{Type: "StatefulSets", Skipped: true},
+		DaemonSets:   ResourceGroup{Type: "DaemonSets", Error: "forbidden"},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, out.String(), "nothing is written before flushing")
+	assert.NoError(t, printer.Flush())
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, SchemaVersion, report.SchemaVersion)
+	assert.Equal(t, "down", report.Operation)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Namespaces, 1)
+	assert.Equal(t, []ScaleInfo{
+		{Name: "web", Replicas: 2, Scaled: true},
+		{Name: "api", Error: "conflict"},
+	}, report.Namespaces[0].Deployments.Resources)
+	assert.True(t, report.Namespaces[0].StatefulSets.Skipped)
+	assert.Equal(t, []ScaleInfo{}, report.Namespaces[0].StatefulSets.Resources)
+	assert.Equal(t, "forbidden", report.Namespaces[0].DaemonSets.Error)
+	assert.Equal(t, []ResourceGroup{}, report.Namespaces[0].Scalables)
+}
+
+func TestStructuredPrinterYAML(t *testing.T) {
+	var out bytes.Buffer
+	printer := NewStructuredPrinterWithWriter(&out, "yaml", "up", false)
+	assert.NoError(t, printer.PrintNamespaceResult(NamespaceResult{Namespace: "default", Excluded: true}))
+	assert.NoError(t, printer.Flush())
+
+	assert.Contains(t, out.String(), "schemaVersion: "+SchemaVersion+"\n")
+	assert.Contains(t, out.String(), "operation: up\n")
+	assert.Contains(t, out.String(), "  namespace: default\n")
+	assert.Contains(t, out.String(), "  excluded: true\n")
+}
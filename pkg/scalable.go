@@ -0,0 +1,242 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/util/retry"
+)
+
+// ScalableResource is a namespaced API resource exposing the scale subresource
+type ScalableResource struct {
+	Resource schema.GroupVersionResource
+	Kind     string
+}
+
+// Name returns the resource name qualified with its group, e.g. "rollouts.argoproj.io"
+func (r ScalableResource) Name() string {
+	return r.Resource.GroupResource().String()
+}
+
+// builtinScalableResources are scaled by their own resource groups and skipped during discovery
+var builtinScalableResources = []schema.GroupResource{
+	{Group: "apps", Resource: "deployments"},
+	{Group: "apps", Resource: "statefulsets"},
+}
+
+// GetScalableResources discovers every namespaced resource exposing the scale subresource in its preferred version.
+// Groups that fail discovery are reported in the returned error while the remaining resources are still returned.
+func GetScalableResources(discoveryClient discovery.DiscoveryInterface) ([]ScalableResource, error) {
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("error discovering API groups: %w", err)
+	}
+
+	var resultError error
+	var resources []ScalableResource
+	for _, group := range groups.Groups {
+		groupVersion := group.PreferredVersion.GroupVersion
+		list, err := discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error discovering resources for %s: %w", groupVersion, err), resultError)
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error parsing group version %s: %w", list.GroupVersion, err), resultError)
+			continue
+		}
+		for _, subresource := range list.APIResources {
+			parent, sub, found := strings.Cut(subresource.Name, "/")
+			if !found || sub != "scale" {
+				continue
+			}
+			if slices.Contains(builtinScalableResources, gv.WithResource(parent).GroupResource()) {
+				continue
+			}
+			for _, r := range list.APIResources {
+				if r.Name == parent && r.Namespaced && slices.Contains(r.Verbs, "list") && slices.Contains(r.Verbs, "patch") {
+					resources = append(resources, ScalableResource{Resource: gv.WithResource(parent), Kind: r.Kind})
+				}
+			}
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name() < resources[j].Name()
+	})
+	return resources, resultError
+}
+
+// GetScalableObjects lists the objects of a scalable resource in a namespace.
+// Objects managed by a controller are left out, since scaling their owner already scales them.
+func GetScalableObjects(ctx context.Context, dynamicClient dynamic.Interface, namespace string, resource ScalableResource, listOptions metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	objects, err := dynamicClient.Resource(resource.Resource).Namespace(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %w", resource.Name(), err)
+	}
+	objects.Items = slices.DeleteFunc(objects.Items, func(o unstructured.Unstructured) bool {
+		return metav1.GetControllerOf(&o) != nil
+	})
+	return objects, nil
+}
+
+func DownscaleScalableObjects(ctx context.Context, dynamicClient dynamic.Interface, scaleClient scale.ScalesGetter, resource ScalableResource, objects *unstructured.UnstructuredList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, o := range objects.Items {
+		if IsExcluded(&o) {
+			results = append(results, excludedInfo(o.GetName()))
+			continue
+		}
+		downscaled, originalReplicas, err := downscaleScalableObject(ctx, dynamicClient, scaleClient, resource, o.GetNamespace(), o.GetName(), dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error scaling down %s %s: %w", resource.Name(), o.GetName(), err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     o.GetName(),
+			Replicas: originalReplicas,
+			Scaled:   downscaled,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !downscaled {
+			info.Warning = "already downscaled"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+func UpscaleScalableObjects(ctx context.Context, dynamicClient dynamic.Interface, scaleClient scale.ScalesGetter, resource ScalableResource, objects *unstructured.UnstructuredList, dryRun bool) ([]ScaleInfo, error) {
+	var resultError error
+	var results []ScaleInfo
+	for _, o := range objects.Items {
+		if IsExcluded(&o) {
+			results = append(results, excludedInfo(o.GetName()))
+			continue
+		}
+		upscaled, replicas, err := upscaleScalableObject(ctx, dynamicClient, scaleClient, resource, o.GetNamespace(), o.GetName(), dryRun)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error scaling up %s %s: %w", resource.Name(), o.GetName(), err), resultError)
+		}
+		info := ScaleInfo{
+			Name:     o.GetName(),
+			Replicas: replicas,
+			Scaled:   upscaled,
+		}
+		if err != nil {
+			info.Error = err.Error()
+		} else if !upscaled {
+			info.Warning = "already scaled up"
+		}
+		results = append(results, info)
+	}
+	return results, resultError
+}
+
+// annotationPatch builds a JSON merge patch setting an annotation, or removing it when value is nil
+func annotationPatch(key string, value *string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]*string{key: value},
+		},
+	})
+}
+
+func patchScalableAnnotation(ctx context.Context, dynamicClient dynamic.Interface, resource ScalableResource, namespace string, name string, value *string) error {
+	patch, err := annotationPatch(replicasAnnotation, value)
+	if err != nil {
+		return err
+	}
+	_, err = dynamicClient.Resource(resource.Resource).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func downscaleScalableObject(ctx context.Context, dynamicClient dynamic.Interface, scaleClient scale.ScalesGetter, resource ScalableResource, namespace string, name string, dryRun bool) (bool, int32, error) {
+	var originalReplicas int32
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o, err := dynamicClient.Resource(resource.Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		s, err := scaleClient.Scales(namespace).Get(ctx, resource.Resource.GroupResource(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		_, downscaled := o.GetAnnotations()[replicasAnnotation]
+		if !downscaled || s.Spec.Replicas > 0 {
+			originalReplicas = s.Spec.Replicas
+			if dryRun {
+				w = true
+				return nil
+			}
+			// The original replica count is recorded before scaling, so that it is never lost if scaling fails
+			if !downscaled {
+				replicas := fmt.Sprintf("%d", s.Spec.Replicas)
+				if err := patchScalableAnnotation(ctx, dynamicClient, resource, namespace, name, &replicas); err != nil {
+					return err
+				}
+			}
+			s.Spec.Replicas = 0
+			_, err := scaleClient.Scales(namespace).Update(ctx, resource.Resource.GroupResource(), s, metav1.UpdateOptions{})
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, originalReplicas, err
+}
+
+func upscaleScalableObject(ctx context.Context, dynamicClient dynamic.Interface, scaleClient scale.ScalesGetter, resource ScalableResource, namespace string, name string, dryRun bool) (bool, int32, error) {
+	var targetReplicas int32
+	w := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o, err := dynamicClient.Resource(resource.Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		replicas, downscaled := o.GetAnnotations()[replicasAnnotation]
+		if downscaled {
+			intReplicas, err := strconv.ParseInt(replicas, 10, 32)
+			if err != nil {
+				return fmt.Errorf("error converting replicas to int: %w", err)
+			}
+			targetReplicas = int32(intReplicas)
+			if dryRun {
+				w = true
+				return nil
+			}
+			s, err := scaleClient.Scales(namespace).Get(ctx, resource.Resource.GroupResource(), name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			s.Spec.Replicas = targetReplicas
+			if _, err := scaleClient.Scales(namespace).Update(ctx, resource.Resource.GroupResource(), s, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			err = patchScalableAnnotation(ctx, dynamicClient, resource, namespace, name, nil)
+			if err == nil {
+				w = true
+			}
+			return err
+		}
+		return nil
+	})
+	return w, targetReplicas, err
+}
@@ -0,0 +1,207 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	scalefake "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var rollouts = ScalableResource{
+	Resource: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"},
+	Kind:     "Rollout",
+}
+
+func newRollout(name string, annotations map[string]string, owners ...metav1.OwnerReference) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetAPIVersion("argoproj.io/v1alpha1")
+	o.SetKind("Rollout")
+	o.SetNamespace("default")
+	o.SetName(name)
+	o.SetAnnotations(annotations)
+	o.SetOwnerReferences(owners)
+	return o
+}
+
+func newScalableDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{rollouts.Resource: "RolloutList"},
+		objects...,
+	)
+}
+
+// newFakeScaleClient returns a scale client serving the scale subresource from the given replica counts
+func newFakeScaleClient(replicas map[string]int32) *scalefake.FakeScaleClient {
+	scaleClient := &scalefake.FakeScaleClient{}
+	scaleClient.AddReactor("get", rollouts.Resource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: action.GetNamespace()},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: replicas[name]},
+		}, nil
+	})
+	scaleClient.AddReactor("update", rollouts.Resource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		s := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		replicas[s.Name] = s.Spec.Replicas
+		return true, s, nil
+	})
+	return scaleClient
+}
+
+func TestGetScalableResources(t *testing.T) {
+	clientset := testclient.NewClientset()
+	discoveryClient := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"list", "patch"}},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "argoproj.io/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "rollouts", Kind: "Rollout", Namespaced: true, Verbs: []string{"list", "patch"}},
+				{Name: "rollouts/scale", Kind: "Scale", Namespaced: true},
+				{Name: "rollouts/status", Kind: "Rollout", Namespaced: true},
+				{Name: "analysistemplates", Kind: "AnalysisTemplate", Namespaced: true, Verbs: []string{"list", "patch"}},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "clusterscalers", Kind: "ClusterScaler", Namespaced: false, Verbs: []string{"list", "patch"}},
+				{Name: "clusterscalers/scale", Kind: "Scale", Namespaced: false},
+			},
+		},
+	}
+
+	resources, err := GetScalableResources(discoveryClient)
+	assert.NoError(t, err)
+	assert.Equal(t, []ScalableResource{rollouts}, resources)
+}
+
+func TestGetScalableObjects(t *testing.T) {
+	ctx := context.Background()
+	owner := metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Owner", Name: "owner", UID: "1", Controller: boolPtr(true)}
+	dynamicClient := newScalableDynamicClient(newRollout("test", nil), newRollout("owned", nil, owner))
+
+	objects, err := GetScalableObjects(ctx, dynamicClient, "default", rollouts, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, objects.Items, 1)
+	assert.Equal(t, "test", objects.Items[0].GetName())
+}
+
+func TestDownscaleScalableObjects(t *testing.T) {
+	testCases := []struct {
+		name               string
+		rollout            *unstructured.Unstructured
+		replicas           int32
+		expectedDownscaled int
+		expectedOldScale   string
+	}{
+		{
+			name:               "When the object was not previously downscaled then it is downscaled",
+			rollout:            newRollout("test", nil),
+			replicas:           2,
+			expectedDownscaled: 1,
+			expectedOldScale:   "2",
+		},
+		{
+			name:               "When the object was previously downscaled then nothing happens",
+			rollout:            newRollout("test", map[string]string{replicasAnnotation: "2"}),
+			replicas:           0,
+			expectedDownscaled: 0,
+			expectedOldScale:   "2",
+		},
+		{
+			name:               "When the object has the downscaled annotation but the replicas are not 0 then it gets downscaled",
+			rollout:            newRollout("test", map[string]string{replicasAnnotation: "2"}),
+			replicas:           1,
+			expectedDownscaled: 1,
+			expectedOldScale:   "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := newScalableDynamicClient(tc.rollout)
+			replicas := map[string]int32{"test": tc.replicas}
+			scaleClient := newFakeScaleClient(replicas)
+
+			objects, err := GetScalableObjects(ctx, dynamicClient, "default", rollouts, metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			downscaledInfos, err := DownscaleScalableObjects(ctx, dynamicClient, scaleClient, rollouts, objects, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDownscaled, countScaled(downscaledInfos))
+			assert.Equal(t, int32(0), replicas["test"])
+
+			o, err := dynamicClient.Resource(rollouts.Resource).Namespace("default").Get(ctx, "test", metav1.GetOptions{})
+			assert.NoError(t, err)
+			oldScale, downscaled := o.GetAnnotations()[replicasAnnotation]
+			assert.True(t, downscaled)
+			assert.Equal(t, tc.expectedOldScale, oldScale)
+		})
+	}
+}
+
+func TestUpscaleScalableObjects(t *testing.T) {
+	testCases := []struct {
+		name             string
+		rollout          *unstructured.Unstructured
+		replicas         int32
+		expectedUpscaled int
+		expectedReplicas int32
+	}{
+		{
+			name:             "When the object was previously downscaled then it is upscaled",
+			rollout:          newRollout("test", map[string]string{replicasAnnotation: "2"}),
+			replicas:         0,
+			expectedUpscaled: 1,
+			expectedReplicas: 2,
+		},
+		{
+			name:             "When the object was not previously downscaled then nothing happens",
+			rollout:          newRollout("test", nil),
+			replicas:         1,
+			expectedUpscaled: 0,
+			expectedReplicas: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dynamicClient := newScalableDynamicClient(tc.rollout)
+			replicas := map[string]int32{"test": tc.replicas}
+			scaleClient := newFakeScaleClient(replicas)
+
+			objects, err := GetScalableObjects(ctx, dynamicClient, "default", rollouts, metav1.ListOptions{})
+			assert.NoError(t, err)
+
+			upscaledInfos, err := UpscaleScalableObjects(ctx, dynamicClient, scaleClient, rollouts, objects, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedUpscaled, countScaled(upscaledInfos))
+			assert.Equal(t, tc.expectedReplicas, replicas["test"])
+
+			o, err := dynamicClient.Resource(rollouts.Resource).Namespace("default").Get(ctx, "test", metav1.GetOptions{})
+			assert.NoError(t, err)
+			_, present := o.GetAnnotations()[replicasAnnotation]
+			assert.False(t, present)
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sleepScheduleAnnotation = "szero/sleep-schedule"
+const sleepCronAnnotation = "szero/sleep-cron"
+const wakeCronAnnotation = "szero/wake-cron"
+const scheduleTimezoneAnnotation = "szero/schedule-timezone"
+
+// Schedule tells whether a namespace should be asleep, i.e. downscaled, at a given time
+type Schedule interface {
+	Asleep(t time.Time) bool
+}
+
+// ParseSchedule reads the schedule of a namespace from its annotations, or returns nil if it has none.
+//
+// The sleep-schedule annotation lists the time ranges the namespace sleeps through, separated by semicolons,
+// e.g. "Mon-Fri 20:00-07:00; Sat-Sun". Ranges ending before they start run into the next day, and days
+// without a time range are slept through entirely, on until the end of such a range starting the next day.
+// Alternatively, the sleep-cron and wake-cron annotations hold cron expressions for when to go to sleep and wake up,
+// e.g. "0 20 * * 1-5" and "0 7 * * 1-5". Times are in the time zone of the schedule-timezone annotation, UTC by default.
+func ParseSchedule(annotations map[string]string) (Schedule, error) {
+	windows, hasWindows := annotations[sleepScheduleAnnotation]
+	sleepCron, hasSleepCron := annotations[sleepCronAnnotation]
+	wakeCron, hasWakeCron := annotations[wakeCronAnnotation]
+	if !hasWindows && !hasSleepCron && !hasWakeCron {
+		return nil, nil
+	}
+
+	location := time.UTC
+	if name, found := annotations[scheduleTimezoneAnnotation]; found {
+		var err error
+		location, err = time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", scheduleTimezoneAnnotation, err)
+		}
+	}
+
+	if hasWindows {
+		if hasSleepCron || hasWakeCron {
+			return nil, fmt.Errorf("%s can't be combined with %s and %s", sleepScheduleAnnotation, sleepCronAnnotation, wakeCronAnnotation)
+		}
+		schedule, err := parseWindowSchedule(windows, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", sleepScheduleAnnotation, err)
+		}
+		return schedule, nil
+	}
+
+	if !hasSleepCron || !hasWakeCron {
+		return nil, fmt.Errorf("%s and %s must be set together", sleepCronAnnotation, wakeCronAnnotation)
+	}
+	sleep, err := parseCron(sleepCron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", sleepCronAnnotation, err)
+	}
+	wake, err := parseCron(wakeCron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", wakeCronAnnotation, err)
+	}
+	return cronSchedule{location: location, sleep: sleep, wake: wake}, nil
+}
+
+var weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+var months = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+
+// sleepWindow is a time range slept through on some days of the week
+type sleepWindow struct {
+	days   [7]bool // indexed by time.Weekday
+	allDay bool
+	start  int // minutes since midnight
+	end    int // minutes since midnight, before start when the range runs into the next day
+}
+
+type windowSchedule struct {
+	location *time.Location
+	windows  []sleepWindow
+}
+
+func (s windowSchedule) Asleep(t time.Time) bool {
+	t = t.In(s.location)
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range s.windows {
+		switch {
+		case w.allDay:
+			if w.days[today] {
+				return true
+			}
+		case w.start < w.end:
+			if w.days[today] && minute >= w.start && minute < w.end {
+				return true
+			}
+		default:
+			// After a day slept through, the namespace keeps sleeping until the range starting today ends,
+			// e.g. until Monday 07:00 with "Mon-Fri 20:00-07:00; Sat-Sun"
+			carriedOver := w.days[today] && s.sleptAllDay(yesterday)
+			if (w.days[today] && minute >= w.start) || ((w.days[yesterday] || carriedOver) && minute < w.end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s windowSchedule) sleptAllDay(day int) bool {
+	for _, w := range s.windows {
+		if w.allDay && w.days[day] {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWindowSchedule(value string, location *time.Location) (windowSchedule, error) {
+	schedule := windowSchedule{location: location}
+	for _, entry := range strings.Split(value, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return schedule, fmt.Errorf("expected days and an optional time range, got %q", strings.TrimSpace(entry))
+		}
+
+		w := sleepWindow{allDay: len(fields) == 1}
+		var err error
+		w.days, err = parseDays(fields[0])
+		if err != nil {
+			return schedule, err
+		}
+		if !w.allDay {
+			start, end, found := strings.Cut(fields[1], "-")
+			if !found {
+				return schedule, fmt.Errorf("expected a time range like 20:00-07:00, got %q", fields[1])
+			}
+			if w.start, err = parseTimeOfDay(start); err != nil {
+				return schedule, err
+			}
+			if w.end, err = parseTimeOfDay(end); err != nil {
+				return schedule, err
+			}
+			if w.start == w.end {
+				return schedule, fmt.Errorf("time range %q is empty", fields[1])
+			}
+		}
+		schedule.windows = append(schedule.windows, w)
+	}
+	if len(schedule.windows) == 0 {
+		return schedule, fmt.Errorf("no time ranges")
+	}
+	return schedule, nil
+}
+
+// parseDays parses days of the week such as "Mon-Fri", "Sat,Sun" or "*"
+func parseDays(value string) ([7]bool, error) {
+	var days [7]bool
+	for _, part := range strings.Split(value, ",") {
+		if part == "*" {
+			return [7]bool{true, true, true, true, true, true, true}, nil
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, found := weekdays[strings.ToLower(first)]
+		if !found {
+			return days, fmt.Errorf("unknown day %q", first)
+		}
+		to := from
+		if isRange {
+			if to, found = weekdays[strings.ToLower(last)]; !found {
+				return days, fmt.Errorf("unknown day %q", last)
+			}
+		}
+		// Ranges may wrap around the end of the week, e.g. Fri-Mon
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+type cronSchedule struct {
+	location *time.Location
+	sleep    cronExpression
+	wake     cronExpression
+}
+
+// Asleep reports whether the namespace went to sleep more recently than it woke up
+func (s cronSchedule) Asleep(t time.Time) bool {
+	t = t.In(s.location)
+	sleep := s.sleep.previous(t)
+	return !sleep.IsZero() && sleep.After(s.wake.previous(t))
+}
+
+// cronExpression is a standard five-field cron expression, each field held as a bit set of the values it matches
+type cronExpression struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek uint64
+	anyDayOfMonth, anyDayOfWeek                     bool
+}
+
+func parseCron(value string) (cronExpression, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 5 {
+		return cronExpression{}, fmt.Errorf("expected 5 fields in cron expression %q", value)
+	}
+
+	var c cronExpression
+	var err error
+	if c.minutes, _, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return c, err
+	}
+	if c.hours, _, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return c, err
+	}
+	if c.daysOfMonth, c.anyDayOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return c, err
+	}
+	if c.months, _, err = parseCronField(fields[3], 1, 12, months); err != nil {
+		return c, err
+	}
+	if c.daysOfWeek, c.anyDayOfWeek, err = parseCronField(fields[4], 0, 7, weekdays); err != nil {
+		return c, err
+	}
+	// Both 0 and 7 stand for Sunday
+	if c.daysOfWeek&(1<<7) != 0 {
+		c.daysOfWeek |= 1
+	}
+	return c, nil
+}
+
+// parseCronField parses a cron field made of values, ranges and steps, e.g. "*/15", "1-5" or "mon,wed,fri".
+// It also reports whether the field is "*".
+func parseCronField(value string, low int, high int, names map[string]int) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		from, to := low, high
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = parseCronValue(first, low, high, names); err != nil {
+				return 0, false, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseCronValue(last, low, high, names); err != nil {
+					return 0, false, err
+				}
+			} else if hasStep {
+				to = high
+			}
+			if from > to {
+				return 0, false, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, value == "*", nil
+}
+
+func parseCronValue(value string, low int, high int, names map[string]int) (int, error) {
+	if v, found := names[strings.ToLower(value)]; found {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < low || v > high {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", value, low, high)
+	}
+	return v, nil
+}
+
+func (c cronExpression) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := c.daysOfWeek&(1<<int(t.Weekday())) != 0
+	// As in cron, a day matches either field when both are restricted
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// previous returns the latest time at or before t matching the expression, or the zero time if there is none
+// in the five years before t
+func (c cronExpression) previous(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	limit := t.AddDate(-5, 0, 0)
+	for t.After(limit) {
+		switch {
+		case c.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case c.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+		case c.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(-time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	officeHours := map[string]string{sleepScheduleAnnotation: "Mon-Fri 20:00-07:00; Sat-Sun"}
+	officeHoursCron := map[string]string{sleepCronAnnotation: "0 20 * * 1-5", wakeCronAnnotation: "0 7 * * mon-fri"}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		at          time.Time
+		asleep      bool
+	}{
+		{
+			name:        "When it is a weekday afternoon then the namespace is awake",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 5, 15, 0, 0, 0, time.UTC), // Wednesday
+			asleep:      false,
+		},
+		{
+			name:        "When it is a weekday night then the namespace is asleep",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 5, 22, 0, 0, 0, time.UTC),
+			asleep:      true,
+		},
+		{
+			name:        "When the night range runs into the next morning then the namespace is asleep until it ends",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 6, 6, 59, 0, 0, time.UTC),
+			asleep:      true,
+		},
+		{
+			name:        "When the night range has ended then the namespace is awake",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 6, 7, 0, 0, 0, time.UTC),
+			asleep:      false,
+		},
+		{
+			name:        "When it is the weekend then the namespace is asleep all day",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC), // Saturday
+			asleep:      true,
+		},
+		{
+			name:        "When the weekend is followed by a night range then the namespace sleeps until it ends on Monday",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC), // Monday
+			asleep:      true,
+		},
+		{
+			name:        "When the night range after the weekend has ended then the namespace is awake on Monday",
+			annotations: officeHours,
+			at:          time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC),
+			asleep:      false,
+		},
+		{
+			name:        "When the day before was not slept through then the morning of a night range is awake",
+			annotations: map[string]string{sleepScheduleAnnotation: "Mon 20:00-07:00"},
+			at:          time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC), // Monday
+			asleep:      false,
+		},
+		{
+			name:        "When the schedule has a time zone then times are in that time zone",
+			annotations: map[string]string{sleepScheduleAnnotation: "* 20:00-07:00", scheduleTimezoneAnnotation: "Europe/Berlin"},
+			at:          time.Date(2025, 3, 5, 20, 30, 0, 0, berlin),
+			asleep:      true,
+		},
+		{
+			name:        "When the cron schedule last went to sleep then the namespace is asleep",
+			annotations: officeHoursCron,
+			at:          time.Date(2025, 3, 5, 22, 0, 0, 0, time.UTC),
+			asleep:      true,
+		},
+		{
+			name:        "When the cron schedule last woke up then the namespace is awake",
+			annotations: officeHoursCron,
+			at:          time.Date(2025, 3, 5, 7, 0, 0, 0, time.UTC),
+			asleep:      false,
+		},
+		{
+			name:        "When the cron schedule has no wake up over the weekend then the namespace sleeps through it",
+			annotations: officeHoursCron,
+			at:          time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC), // Sunday
+			asleep:      true,
+		},
+		{
+			name:        "When the cron schedule uses steps then every matching minute counts",
+			annotations: map[string]string{sleepCronAnnotation: "*/20 * * * *", wakeCronAnnotation: "10-59/20 * * * *"},
+			at:          time.Date(2025, 3, 5, 12, 45, 0, 0, time.UTC),
+			asleep:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.annotations)
+			assert.NoError(t, err)
+			assert.Equal(t, test.asleep, schedule.Asleep(test.at))
+		})
+	}
+}
+
+func TestParseScheduleWithoutAnnotations(t *testing.T) {
+	schedule, err := ParseSchedule(map[string]string{"other": "value"})
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+}
+
+func TestParseInvalidSchedule(t *testing.T) {
+	tests := []map[string]string{
+		{sleepScheduleAnnotation: "Mon-Fri 20:00"},
+		{sleepScheduleAnnotation: "Someday"},
+		{sleepScheduleAnnotation: "Mon 25:00-07:00"},
+		{sleepScheduleAnnotation: "Mon 07:00-07:00"},
+		{sleepScheduleAnnotation: " ; "},
+		{sleepScheduleAnnotation: "Sat", scheduleTimezoneAnnotation: "Mars/Olympus"},
+		{sleepScheduleAnnotation: "Sat", sleepCronAnnotation: "0 20 * * *"},
+		{sleepCronAnnotation: "0 20 * * *"},
+		{sleepCronAnnotation: "0 20 * *", wakeCronAnnotation: "0 7 * * *"},
+		{sleepCronAnnotation: "0 24 * * *", wakeCronAnnotation: "0 7 * * *"},
+		{sleepCronAnnotation: "0 20 * * *", wakeCronAnnotation: "*/0 7 * * *"},
+		{sleepCronAnnotation: "0 20 * * *", wakeCronAnnotation: "0 7 * * 5-1"},
+	}
+
+	for _, annotations := range tests {
+		_, err := ParseSchedule(annotations)
+		assert.Error(t, err, annotations)
+	}
+}
@@ -0,0 +1,174 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// ScheduleName is the name of the ServiceAccount, Role and RoleBinding the sleep schedule CronJobs run with
+const ScheduleName = "szero-schedule"
+
+const scheduleDownName = ScheduleName + "-down"
+const scheduleUpName = ScheduleName + "-up"
+
+// SleepSchedule describes the CronJobs bringing a namespace down and back up on a schedule
+type SleepSchedule struct {
+	Namespace string     `json:"namespace"`
+	Down      string     `json:"down"` // cron expression
+	Up        string     `json:"up"`   // cron expression
+	TimeZone  string     `json:"timeZone"`
+	Image     string     `json:"image"`
+	DownArgs  []string   `json:"-"` // arguments for the szero binary in the image when going down
+	UpArgs    []string   `json:"-"` // arguments for the szero binary in the image when going up
+	Features  Features   `json:"-"` // what the CronJobs do, for the Role they run with
+	LastDown  *time.Time `json:"lastDown,omitempty"`
+	LastUp    *time.Time `json:"lastUp,omitempty"`
+}
+
+// ScheduleObjects builds the CronJobs, ServiceAccount, Role and RoleBinding running szero on a sleep schedule
+func ScheduleObjects(schedule SleepSchedule) ([]runtime.Object, error) {
+	o, err := newScheduleObjects(schedule)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{o.serviceAccount, o.role, o.roleBinding, o.down, o.up}, nil
+}
+
+// ApplySchedule creates the objects running szero on a sleep schedule, replacing a previous schedule of the namespace
+func ApplySchedule(ctx context.Context, clientset kubernetes.Interface, schedule SleepSchedule) error {
+	o, err := newScheduleObjects(schedule)
+	if err != nil {
+		return err
+	}
+	if err := applyRBAC(ctx, clientset, o.serviceAccount, o.role, o.roleBinding); err != nil {
+		return fmt.Errorf("error creating schedule permissions: %w", err)
+	}
+	for _, cronJob := range []*batchv1.CronJob{o.down, o.up} {
+		if _, err := applyCronJob(ctx, clientset, cronJob); err != nil {
+			return fmt.Errorf("error creating schedule cronjob %s: %w", cronJob.Name, err)
+		}
+	}
+	return nil
+}
+
+type scheduleObjects struct {
+	serviceAccount *corev1.ServiceAccount
+	role           *rbacv1.Role
+	roleBinding    *rbacv1.RoleBinding
+	down           *batchv1.CronJob
+	up             *batchv1.CronJob
+}
+
+func newScheduleObjects(schedule SleepSchedule) (scheduleObjects, error) {
+	if _, err := parseCron(schedule.Down); err != nil {
+		return scheduleObjects{}, fmt.Errorf("invalid down schedule: %w", err)
+	}
+	if _, err := parseCron(schedule.Up); err != nil {
+		return scheduleObjects{}, fmt.Errorf("invalid up schedule: %w", err)
+	}
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		return scheduleObjects{}, fmt.Errorf("invalid time zone: %w", err)
+	}
+
+	o := scheduleObjects{
+		down: inClusterCronJob(scheduleDownName, schedule.Namespace, schedule.Down, schedule.TimeZone, schedule.Image, ScheduleName, schedule.DownArgs),
+		up:   inClusterCronJob(scheduleUpName, schedule.Namespace, schedule.Up, schedule.TimeZone, schedule.Image, ScheduleName, schedule.UpArgs),
+	}
+	o.serviceAccount, o.role, o.roleBinding = inClusterRBAC(ScheduleName, schedule.Namespace, RequiredRules(schedule.Features))
+	return o, nil
+}
+
+// GetSchedule returns the sleep schedule of a namespace, or nil if it has none
+func GetSchedule(ctx context.Context, clientset kubernetes.Interface, namespace string) (*SleepSchedule, error) {
+	schedule := &SleepSchedule{Namespace: namespace}
+	found := false
+	for _, name := range []string{scheduleDownName, scheduleUpName} {
+		cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting schedule cronjob %s: %w", name, err)
+		}
+		if !isManaged(cronJob) {
+			continue
+		}
+		found = true
+
+		var lastRun *time.Time
+		if cronJob.Status.LastScheduleTime != nil {
+			lastRun = &cronJob.Status.LastScheduleTime.Time
+		}
+		if cronJob.Spec.TimeZone != nil {
+			schedule.TimeZone = *cronJob.Spec.TimeZone
+		}
+		if containers := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers; len(containers) > 0 {
+			schedule.Image = containers[0].Image
+		}
+		if name == scheduleDownName {
+			schedule.Down, schedule.LastDown = cronJob.Spec.Schedule, lastRun
+		} else {
+			schedule.Up, schedule.LastUp = cronJob.Spec.Schedule, lastRun
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule removes the sleep schedule of a namespace along with the permissions it ran with, if there is one
+func DeleteSchedule(ctx context.Context, clientset kubernetes.Interface, namespace string, dryRun bool) (bool, error) {
+	schedule, err := GetSchedule(ctx, clientset, namespace)
+	if err != nil || schedule == nil {
+		return false, err
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	// Objects szero did not create are left alone
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: new(metav1.DeletePropagationBackground)}
+	var resultError error
+	for _, name := range []string{scheduleDownName, scheduleUpName} {
+		if _, err := deleteManaged(ctx, clientset.BatchV1().CronJobs(namespace), name, deleteOptions); err != nil {
+			resultError = errors.Join(fmt.Errorf("error deleting cronjob %s: %w", name, err), resultError)
+		}
+	}
+	if _, err := deleteManaged(ctx, clientset.RbacV1().RoleBindings(namespace), ScheduleName, deleteOptions); err != nil {
+		resultError = errors.Join(fmt.Errorf("error deleting role binding %s: %w", ScheduleName, err), resultError)
+	}
+	if _, err := deleteManaged(ctx, clientset.RbacV1().Roles(namespace), ScheduleName, deleteOptions); err != nil {
+		resultError = errors.Join(fmt.Errorf("error deleting role %s: %w", ScheduleName, err), resultError)
+	}
+	if _, err := deleteManaged(ctx, clientset.CoreV1().ServiceAccounts(namespace), ScheduleName, deleteOptions); err != nil {
+		resultError = errors.Join(fmt.Errorf("error deleting service account %s: %w", ScheduleName, err), resultError)
+	}
+	return resultError == nil, resultError
+}
+
+// WriteManifests writes objects as a multi-document YAML stream, ready for kubectl apply -f
+func WriteManifests(w io.Writer, objects []runtime.Object) error {
+	for _, o := range objects {
+		data, err := yaml.Marshal(o)
+		if err != nil {
+			return fmt.Errorf("error encoding manifest: %w", err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
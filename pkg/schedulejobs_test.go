@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func newSleepSchedule() SleepSchedule {
+	return SleepSchedule{
+		Namespace: "staging",
+		Down:      "0 20 * * 1-5",
+		Up:        "0 7 * * 1-5",
+		TimeZone:  "Europe/Berlin",
+		Image:     "ghcr.io/example/szero:latest",
+		DownArgs:  []string{"down", "--namespace=staging"},
+		UpArgs:    []string{"up", "--namespace=staging"},
+		Features:  Features{Deployments: true, StatefulSets: true},
+	}
+}
+
+func TestScheduleObjects(t *testing.T) {
+	objects, err := ScheduleObjects(newSleepSchedule())
+	assert.NoError(t, err)
+	assert.Len(t, objects, 5)
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteManifests(&out, objects))
+	assert.Equal(t, 5, strings.Count(out.String(), "---\n"))
+	assert.Contains(t, out.String(), "kind: ServiceAccount\n")
+	assert.Contains(t, out.String(), "kind: RoleBinding\n")
+	assert.Contains(t, out.String(), "  name: "+scheduleDownName+"\n")
+	assert.Contains(t, out.String(), "  schedule: 0 7 * * 1-5\n")
+	assert.Contains(t, out.String(), "  timeZone: Europe/Berlin\n")
+}
+
+func TestScheduleObjectsWithInvalidSchedule(t *testing.T) {
+	schedule := newSleepSchedule()
+	schedule.Down = "at eight"
+	_, err := ScheduleObjects(schedule)
+	assert.Error(t, err)
+
+	schedule = newSleepSchedule()
+	schedule.TimeZone = "Mars/Olympus"
+	_, err = ScheduleObjects(schedule)
+	assert.Error(t, err)
+}
+
+func TestApplyAndDeleteSchedule(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset()
+
+	schedule, err := GetSchedule(ctx, clientset, "staging")
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	assert.NoError(t, ApplySchedule(ctx, clientset, newSleepSchedule()))
+	down, err := clientset.BatchV1().CronJobs("staging").Get(ctx, scheduleDownName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, IsExcluded(down))
+	assert.Equal(t, []string{"down", "--namespace=staging"}, down.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args)
+	role, err := clientset.RbacV1().Roles("staging").Get(ctx, ScheduleName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, RequiredRules(Features{Deployments: true, StatefulSets: true}), role.Rules)
+	assert.NotContains(t, role.Rules[1].Resources, "daemonsets")
+
+	// Creating the schedule again replaces it
+	updated := newSleepSchedule()
+	updated.Up = "30 6 * * 1-5"
+	assert.NoError(t, ApplySchedule(ctx, clientset, updated))
+	schedule, err = GetSchedule(ctx, clientset, "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, &SleepSchedule{
+		Namespace: "staging",
+		Down:      "0 20 * * 1-5",
+		Up:        "30 6 * * 1-5",
+		TimeZone:  "Europe/Berlin",
+		Image:     "ghcr.io/example/szero:latest",
+	}, schedule)
+
+	deleted, err := DeleteSchedule(ctx, clientset, "staging", true)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = clientset.BatchV1().CronJobs("staging").Get(ctx, scheduleUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	deleted, err = DeleteSchedule(ctx, clientset, "staging", false)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	cronJobs, err := clientset.BatchV1().CronJobs("staging").List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, cronJobs.Items)
+	_, err = clientset.CoreV1().ServiceAccounts("staging").Get(ctx, ScheduleName, metav1.GetOptions{})
+	assert.Error(t, err)
+
+	deleted, err = DeleteSchedule(ctx, clientset, "staging", false)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestApplyScheduleKeepsForeignRoles(t *testing.T) {
+	ctx := context.Background()
+	foreign := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: ScheduleName, Namespace: "staging"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+	}
+	clientset := testclient.NewClientset(foreign)
+
+	assert.Error(t, ApplySchedule(ctx, clientset, newSleepSchedule()))
+	role, err := clientset.RbacV1().Roles("staging").Get(ctx, ScheduleName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, foreign.Rules, role.Rules)
+
+	// Deleting a schedule leaves the objects szero did not create alone
+	assert.NoError(t, clientset.RbacV1().Roles("staging").Delete(ctx, ScheduleName, metav1.DeleteOptions{}))
+	assert.NoError(t, ApplySchedule(ctx, clientset, newSleepSchedule()))
+	_, err = clientset.RbacV1().Roles("staging").Update(ctx, foreign, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	deleted, err := DeleteSchedule(ctx, clientset, "staging", false)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = clientset.RbacV1().Roles("staging").Get(ctx, ScheduleName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.CoreV1().ServiceAccounts("staging").Get(ctx, ScheduleName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestApplyScheduleKeepsForeignCronJobs(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: scheduleDownName, Namespace: "staging"},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 8 * * *"},
+	})
+
+	assert.Error(t, ApplySchedule(ctx, clientset, newSleepSchedule()))
+	schedule, err := GetSchedule(ctx, clientset, "staging")
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+}
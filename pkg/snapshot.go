@@ -0,0 +1,262 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// stateAnnotations are the annotations holding what szero needs to bring a resource back up
+var stateAnnotations = []string{replicasAnnotation, suspendAnnotation, hpaBehaviorAnnotation, kedaPausedAnnotation}
+
+// snapshotResources are the built-in resources whose szero state is captured in snapshots
+var snapshotResources = []schema.GroupVersionResource{
+	deploymentsResource,
+	statefulSetsResource,
+	daemonSetsResource,
+	cronJobsResource,
+	jobsResource,
+	hpasResource,
+	scaledObjectsResource,
+}
+
+// Snapshot holds the state szero stored on downscaled resources, so that it can be restored if the annotations are lost
+type Snapshot struct {
+	SchemaVersion string              `json:"schemaVersion"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	Namespaces    []NamespaceSnapshot `json:"namespaces"`
+}
+
+// NamespaceSnapshot holds the state of the downscaled resources in a namespace
+type NamespaceSnapshot struct {
+	Namespace string             `json:"namespace"`
+	Resources []ResourceSnapshot `json:"resources"`
+}
+
+// ResourceSnapshot holds the state of a single downscaled resource
+type ResourceSnapshot struct {
+	APIVersion   string            `json:"apiVersion"`
+	Resource     string            `json:"resource"` // e.g. "deployments"
+	Kind         string            `json:"kind"`
+	Name         string            `json:"name"`
+	Annotations  map[string]string `json:"annotations,omitempty"`  // szero's state annotations
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"` // for DaemonSets, the node selector before the downscale
+}
+
+// NewSnapshot creates an empty snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Namespaces:    []NamespaceSnapshot{},
+	}
+}
+
+// Namespace returns the snapshot of a namespace, if it is part of the snapshot
+func (s *Snapshot) Namespace(namespace string) (NamespaceSnapshot, bool) {
+	for _, n := range s.Namespaces {
+		if n.Namespace == namespace {
+			return n, true
+		}
+	}
+	return NamespaceSnapshot{}, false
+}
+
+// NamespaceNames returns the names of the namespaces in the snapshot
+func (s *Snapshot) NamespaceNames() []string {
+	names := make([]string, len(s.Namespaces))
+	for i, n := range s.Namespaces {
+		names[i] = n.Namespace
+	}
+	return names
+}
+
+// WriteSnapshotFile writes a snapshot as JSON to path
+func WriteSnapshotFile(path string, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshotFile reads a snapshot written by WriteSnapshotFile
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	return decodeSnapshot(data)
+}
+
+func decodeSnapshot(data []byte) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot: %w", err)
+	}
+	if snapshot.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported snapshot schema version %q, expected %q", snapshot.SchemaVersion, SchemaVersion)
+	}
+	return snapshot, nil
+}
+
+// TakeNamespaceSnapshot captures the state of every resource in a namespace carrying szero's state,
+// including the given scalable resources. Resources that are not installed in the cluster are ignored.
+func TakeNamespaceSnapshot(ctx context.Context, dynamicClient dynamic.Interface, namespace string, scalableResources []ScalableResource) (NamespaceSnapshot, error) {
+	resources := slices.Clone(snapshotResources)
+	for _, r := range scalableResources {
+		resources = append(resources, r.Resource)
+	}
+
+	snapshot := NamespaceSnapshot{Namespace: namespace, Resources: []ResourceSnapshot{}}
+	for _, resource := range resources {
+		objects, err := dynamicClient.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return snapshot, fmt.Errorf("error getting %s: %w", resource.GroupResource(), err)
+		}
+		for _, o := range objects.Items {
+			r := ResourceSnapshot{
+				APIVersion: resource.GroupVersion().String(),
+				Resource:   resource.Resource,
+				Kind:       o.GetKind(),
+				Name:       o.GetName(),
+			}
+			for _, key := range stateAnnotations {
+				if value, found := o.GetAnnotations()[key]; found {
+					if r.Annotations == nil {
+						r.Annotations = map[string]string{}
+					}
+					r.Annotations[key] = value
+				}
+			}
+			nodeSelector, _, _ := unstructured.NestedStringMap(o.Object, "spec", "template", "spec", "nodeSelector")
+			_, noschedule := nodeSelector[noscheduleAnnotation]
+			if noschedule {
+				delete(nodeSelector, noscheduleAnnotation)
+				r.NodeSelector = nodeSelector
+			}
+			if r.Annotations != nil || noschedule {
+				snapshot.Resources = append(snapshot.Resources, r)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// RestoreNamespaceSnapshot puts back the state annotations missing from the resources in a snapshot, and the node
+// selector keys DaemonSets lost since the downscale, so that a regular upscale brings them back to their original
+// state. Changes made to the resources since are kept.
+// It returns the names of the restored resources. Resources that no longer exist are skipped.
+func RestoreNamespaceSnapshot(ctx context.Context, dynamicClient dynamic.Interface, snapshot NamespaceSnapshot, dryRun bool) ([]string, error) {
+	var resultError error
+	var restored []string
+	for _, r := range snapshot.Resources {
+		gv, err := schema.ParseGroupVersion(r.APIVersion)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error parsing api version of %s %s: %w", r.Kind, r.Name, err), resultError)
+			continue
+		}
+		client := dynamicClient.Resource(gv.WithResource(r.Resource)).Namespace(snapshot.Namespace)
+		o, err := client.Get(ctx, r.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error getting %s %s: %w", r.Kind, r.Name, err), resultError)
+			continue
+		}
+
+		missing := map[string]*string{}
+		for key, value := range r.Annotations {
+			if _, found := o.GetAnnotations()[key]; !found {
+				missing[key] = &value
+			}
+		}
+		var nodeSelector map[string]*string
+		if r.Resource == daemonSetsResource.Resource {
+			nodeSelector = missingNodeSelector(o, r.NodeSelector)
+		}
+		if len(missing) == 0 && len(nodeSelector) == 0 {
+			continue
+		}
+		restored = append(restored, r.Kind+"/"+r.Name)
+		if dryRun {
+			continue
+		}
+		changes := map[string]any{}
+		if len(missing) > 0 {
+			changes["metadata"] = map[string]any{"annotations": missing}
+		}
+		if len(nodeSelector) > 0 {
+			changes["spec"] = map[string]any{"template": map[string]any{"spec": map[string]any{"nodeSelector": nodeSelector}}}
+		}
+		patch, err := json.Marshal(changes)
+		if err != nil {
+			resultError = errors.Join(fmt.Errorf("error restoring %s %s: %w", r.Kind, r.Name, err), resultError)
+			continue
+		}
+		if _, err := client.Patch(ctx, r.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			resultError = errors.Join(fmt.Errorf("error restoring %s %s: %w", r.Kind, r.Name, err), resultError)
+		}
+	}
+	return restored, resultError
+}
+
+// missingNodeSelector returns the merge patch putting back the node selector keys a DaemonSet had before the
+// downscale and lost since. Keys it still has are left alone, even when changed, and szero's own node selector is
+// left to the upscale.
+func missingNodeSelector(o *unstructured.Unstructured, original map[string]string) map[string]*string {
+	current, _, _ := unstructured.NestedStringMap(o.Object, "spec", "template", "spec", "nodeSelector")
+	missing := map[string]*string{}
+	for key, value := range original {
+		if _, found := current[key]; !found {
+			missing[key] = &value
+		}
+	}
+	return missing
+}
+
+// IsSnapshotRestored reports whether none of the resources in a snapshot still carries szero's state.
+// Resources that no longer exist count as restored.
+func IsSnapshotRestored(ctx context.Context, dynamicClient dynamic.Interface, snapshot NamespaceSnapshot) (bool, error) {
+	for _, r := range snapshot.Resources {
+		gv, err := schema.ParseGroupVersion(r.APIVersion)
+		if err != nil {
+			return false, fmt.Errorf("error parsing api version of %s %s: %w", r.Kind, r.Name, err)
+		}
+		o, err := dynamicClient.Resource(gv.WithResource(r.Resource)).Namespace(snapshot.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("error getting %s %s: %w", r.Kind, r.Name, err)
+		}
+		for _, key := range stateAnnotations {
+			if _, found := o.GetAnnotations()[key]; found {
+				return false, nil
+			}
+		}
+		nodeSelector, _, _ := unstructured.NestedStringMap(o.Object, "spec", "template", "spec", "nodeSelector")
+		if _, noschedule := nodeSelector[noscheduleAnnotation]; noschedule {
+			return false, nil
+		}
+	}
+	return true, nil
+}
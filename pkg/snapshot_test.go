@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newSnapshotDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}:                     "DeploymentList",
+		{Group: "apps", Version: "v1", Resource: "statefulsets"}:                    "StatefulSetList",
+		{Group: "apps", Version: "v1", Resource: "daemonsets"}:                      "DaemonSetList",
+		{Group: "batch", Version: "v1", Resource: "cronjobs"}:                       "CronJobList",
+		{Group: "batch", Version: "v1", Resource: "jobs"}:                           "JobList",
+		{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscalerList",
+		scaledObjectsResource: "ScaledObjectList",
+		rollouts.Resource:     "RolloutList",
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func newUnstructured(apiVersion string, kind string, name string, annotations map[string]string) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetAPIVersion(apiVersion)
+	o.SetKind(kind)
+	o.SetNamespace("default")
+	o.SetName(name)
+	o.SetAnnotations(annotations)
+	return o
+}
+
+func TestTakeNamespaceSnapshot(t *testing.T) {
+	ctx := context.Background()
+	daemonset := newUnstructured("apps/v1", "DaemonSet", "agent", nil)
+	assert.NoError(t, unstructured.SetNestedStringMap(daemonset.Object, map[string]string{
+		"disk":               "ssd",
+		noscheduleAnnotation: "true",
+	}, "spec", "template", "spec", "nodeSelector"))
+	dynamicClient := newSnapshotDynamicClient(
+		newUnstructured("apps/v1", "Deployment", "web", map[string]string{replicasAnnotation: "3", "other": "ignored"}),
+		newUnstructured("apps/v1", "Deployment", "awake", nil),
+		newUnstructured("batch/v1", "CronJob", "backup", map[string]string{suspendAnnotation: "false"}),
+		newRollout("canary", map[string]string{replicasAnnotation: "2"}),
+		daemonset,
+	)
+
+	snapshot, err := TakeNamespaceSnapshot(ctx, dynamicClient, "default", []ScalableResource{rollouts})
+	assert.NoError(t, err)
+	assert.Equal(t, NamespaceSnapshot{
+		Namespace: "default",
+		Resources: []ResourceSnapshot{
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}},
+			{APIVersion: "apps/v1", Resource: "daemonsets", Kind: "DaemonSet", Name: "agent", NodeSelector: map[string]string{"disk": "ssd"}},
+			{APIVersion: "batch/v1", Resource: "cronjobs", Kind: "CronJob", Name: "backup", Annotations: map[string]string{suspendAnnotation: "false"}},
+			{APIVersion: "argoproj.io/v1alpha1", Resource: "rollouts", Kind: "Rollout", Name: "canary", Annotations: map[string]string{replicasAnnotation: "2"}},
+		},
+	}, snapshot)
+}
+
+func TestRestoreNamespaceSnapshot(t *testing.T) {
+	ctx := context.Background()
+	// The deployment lost its annotations, e.g. because its manifest was applied again while it was downscaled
+	dynamicClient := newSnapshotDynamicClient(
+		newUnstructured("apps/v1", "Deployment", "web", map[string]string{"other": "kept"}),
+		newUnstructured("apps/v1", "Deployment", "api", map[string]string{replicasAnnotation: "1"}),
+	)
+	snapshot := NamespaceSnapshot{
+		Namespace: "default",
+		Resources: []ResourceSnapshot{
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}},
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "api", Annotations: map[string]string{replicasAnnotation: "5"}},
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "deleted", Annotations: map[string]string{replicasAnnotation: "1"}},
+		},
+	}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	restored, err := RestoreNamespaceSnapshot(ctx, dynamicClient, snapshot, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Deployment/web"}, restored)
+	o, err := dynamicClient.Resource(deployments).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, o.GetAnnotations(), replicasAnnotation)
+
+	restored, err = RestoreNamespaceSnapshot(ctx, dynamicClient, snapshot, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Deployment/web"}, restored)
+	o, err = dynamicClient.Resource(deployments).Namespace("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{replicasAnnotation: "3", "other": "kept"}, o.GetAnnotations())
+
+	// Annotations still present on the resource win over the snapshot
+	o, err = dynamicClient.Resource(deployments).Namespace("default").Get(ctx, "api", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", o.GetAnnotations()[replicasAnnotation])
+}
+
+func TestRestoreNamespaceSnapshotNodeSelectors(t *testing.T) {
+	ctx := context.Background()
+	// The manifest of reapplied lost szero's node selector and changed another one, which is kept, edited is still
+	// downscaled but lost one of its node selectors, and untouched is as szero left it
+	reapplied := newUnstructured("apps/v1", "DaemonSet", "reapplied", nil)
+	assert.NoError(t, unstructured.SetNestedStringMap(reapplied.Object, map[string]string{"disk": "hdd", "zone": "a"}, "spec", "template", "spec", "nodeSelector"))
+	edited := newUnstructured("apps/v1", "DaemonSet", "edited", nil)
+	assert.NoError(t, unstructured.SetNestedStringMap(edited.Object, map[string]string{noscheduleAnnotation: "true"}, "spec", "template", "spec", "nodeSelector"))
+	untouched := newUnstructured("apps/v1", "DaemonSet", "untouched", nil)
+	assert.NoError(t, unstructured.SetNestedStringMap(untouched.Object, map[string]string{"disk": "ssd", noscheduleAnnotation: "true"}, "spec", "template", "spec", "nodeSelector"))
+	dynamicClient := newSnapshotDynamicClient(reapplied, edited, untouched)
+	snapshot := NamespaceSnapshot{
+		Namespace: "default",
+		Resources: []ResourceSnapshot{
+			{APIVersion: "apps/v1", Resource: "daemonsets", Kind: "DaemonSet", Name: "reapplied", NodeSelector: map[string]string{"disk": "ssd"}},
+			{APIVersion: "apps/v1", Resource: "daemonsets", Kind: "DaemonSet", Name: "edited", NodeSelector: map[string]string{"disk": "ssd"}},
+			{APIVersion: "apps/v1", Resource: "daemonsets", Kind: "DaemonSet", Name: "untouched", NodeSelector: map[string]string{"disk": "ssd"}},
+		},
+	}
+
+	restored, err := RestoreNamespaceSnapshot(ctx, dynamicClient, snapshot, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DaemonSet/edited"}, restored)
+
+	daemonsets := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	for name, expected := range map[string]map[string]string{
+		"reapplied": {"disk": "hdd", "zone": "a"},
+		"edited":    {"disk": "ssd", noscheduleAnnotation: "true"},
+		"untouched": {"disk": "ssd", noscheduleAnnotation: "true"},
+	} {
+		o, err := dynamicClient.Resource(daemonsets).Namespace("default").Get(ctx, name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		nodeSelector, _, _ := unstructured.NestedStringMap(o.Object, "spec", "template", "spec", "nodeSelector")
+		assert.Equal(t, expected, nodeSelector, name)
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	snapshot := NewSnapshot()
+	snapshot.Namespaces = append(snapshot.Namespaces, NamespaceSnapshot{
+		Namespace: "default",
+		Resources: []ResourceSnapshot{
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}},
+		},
+	})
+
+	assert.NoError(t, WriteSnapshotFile(path, snapshot))
+	read, err := ReadSnapshotFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default"}, read.NamespaceNames())
+	namespaceSnapshot, found := read.Namespace("default")
+	assert.True(t, found)
+	assert.Equal(t, snapshot.Namespaces[0], namespaceSnapshot)
+
+	_, err = decodeSnapshot([]byte(`{"schemaVersion": "szero/v0"}`))
+	assert.Error(t, err)
+}
+
+func TestIsSnapshotRestored(t *testing.T) {
+	ctx := context.Background()
+	snapshot := NamespaceSnapshot{
+		Namespace: "default",
+		Resources: []ResourceSnapshot{
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "web", Annotations: map[string]string{replicasAnnotation: "3"}},
+			{APIVersion: "apps/v1", Resource: "deployments", Kind: "Deployment", Name: "deleted", Annotations: map[string]string{replicasAnnotation: "1"}},
+		},
+	}
+
+	restored, err := IsSnapshotRestored(ctx, newSnapshotDynamicClient(newUnstructured("apps/v1", "Deployment", "web", map[string]string{replicasAnnotation: "3"})), snapshot)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+
+	restored, err = IsSnapshotRestored(ctx, newSnapshotDynamicClient(newUnstructured("apps/v1", "Deployment", "web", nil)), snapshot)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+}
@@ -5,10 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
 )
@@ -17,6 +18,10 @@ func UpscaleStatefulSets(ctx context.Context, clientset kubernetes.Interface, st
 	var resultError error
 	var results []ScaleInfo
 	for _, s := range statefulsets.Items {
+		if IsExcluded(&s) {
+			results = append(results, excludedInfo(s.Name))
+			continue
+		}
 		upscaled, replicas, err := upscaleStatefulset(ctx, clientset, s.Namespace, s.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling up statefulset %s: %w", s.Name, err), resultError)
@@ -26,7 +31,9 @@ func UpscaleStatefulSets(ctx context.Context, clientset kubernetes.Interface, st
 			Replicas: replicas,
 			Scaled:   upscaled,
 		}
-		if !upscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !upscaled {
 			info.Warning = "already scaled up"
 		}
 		results = append(results, info)
@@ -38,6 +45,10 @@ func DownscaleStatefulSets(ctx context.Context, clientset kubernetes.Interface,
 	var resultError error
 	var results []ScaleInfo
 	for _, s := range statefulsets.Items {
+		if IsExcluded(&s) {
+			results = append(results, excludedInfo(s.Name))
+			continue
+		}
 		downscaled, originalReplicas, err := downscaleStatefulset(ctx, clientset, s.Namespace, s.Name, dryRun)
 		if err != nil {
 			resultError = errors.Join(fmt.Errorf("error scaling down statefulset %s: %w", s.Name, err), resultError)
@@ -47,7 +58,9 @@ func DownscaleStatefulSets(ctx context.Context, clientset kubernetes.Interface,
 			Replicas: originalReplicas,
 			Scaled:   downscaled,
 		}
-		if !downscaled {
+		if err != nil {
+			info.Error = err.Error()
+		} else if !downscaled {
 			info.Warning = "already downscaled"
 		}
 		results = append(results, info)
@@ -119,8 +132,8 @@ func downscaleStatefulset(ctx context.Context, clientset kubernetes.Interface, n
 	return w, originalReplicas, err
 }
 
-func GetStatefulSets(ctx context.Context, clientset kubernetes.Interface, namespace string) (*v1.StatefulSetList, error) {
-	statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+func GetStatefulSets(ctx context.Context, clientset kubernetes.Interface, namespace string, listOptions metav1.ListOptions) (*v1.StatefulSetList, error) {
+	statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, listOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error getting statefulsets: %w", err)
 	}
@@ -134,31 +147,34 @@ func IsStatefulSetReady(ss *v1.StatefulSet, downscaled bool) bool {
 	return ss.Status.AvailableReplicas == *ss.Spec.Replicas
 }
 
-func WaitForStatefulSets(ctx context.Context, clientset kubernetes.Interface, statefulsets *v1.StatefulSetList, timeout time.Duration, downscaled bool) error {
-	ticker := time.NewTicker(1 * time.Second)
-	timeoutAfter := time.After(timeout)
-
-	for {
-		select {
-		case <-timeoutAfter:
-			return fmt.Errorf("timeout waiting for statefulsets to reconcile")
-		case <-ticker.C:
-			done := true
-			for _, d := range statefulsets.Items {
-				ss, err := clientset.AppsV1().StatefulSets(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
-				if err != nil {
-					return fmt.Errorf("error getting statefulset %s: %w", d.Name, err)
-				}
-				if IsStatefulSetReady(ss, downscaled) {
-					continue
-				} else {
-					done = false
-					break
-				}
-			}
-			if done {
-				return nil
-			}
+// WaitForStatefulSets waits until the statefulsets that are not excluded reach the desired state, watching them for changes
+func WaitForStatefulSets(ctx context.Context, clientset kubernetes.Interface, statefulsets *v1.StatefulSetList, options WaitOptions) error {
+	var waited []*v1.StatefulSet
+	namespace := ""
+	for i := range statefulsets.Items {
+		if !IsExcluded(&statefulsets.Items[i]) {
+			waited = append(waited, &statefulsets.Items[i])
+			namespace = statefulsets.Items[i].Namespace
 		}
 	}
+
+	return waitUntilReady(ctx, clientset, namespace, waitedKind[*v1.StatefulSet]{
+		resource: "statefulsets",
+		kind:     "StatefulSet",
+		objType:  &v1.StatefulSet{},
+		list: func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().StatefulSets(namespace).List(ctx, listOptions)
+		},
+		watch: func(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().StatefulSets(namespace).Watch(ctx, listOptions)
+		},
+		ready: func(s *v1.StatefulSet) bool { return IsStatefulSetReady(s, options.Downscaled) },
+		replicas: func(s *v1.StatefulSet) (int32, int32) {
+			if options.Downscaled {
+				return s.Status.Replicas, 0
+			}
+			return s.Status.AvailableReplicas, *s.Spec.Replicas
+		},
+		selector: func(s *v1.StatefulSet) *metav1.LabelSelector { return s.Spec.Selector },
+	}, waited, options)
 }
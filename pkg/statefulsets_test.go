@@ -13,7 +13,7 @@ import (
 func TestGetStatefulsets(t *testing.T) {
 	ctx := context.Background()
 	clientset := testclient.NewClientset()
-	statefulsets, err := GetStatefulSets(ctx, clientset, "default")
+	statefulsets, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, statefulsets.Items, 0)
 
@@ -26,7 +26,7 @@ func TestGetStatefulsets(t *testing.T) {
 	_, err = clientset.AppsV1().StatefulSets("default").Create(ctx, &statefulset, metav1.CreateOptions{})
 	assert.NoError(t, err)
 
-	newStatefulsets, err := GetStatefulSets(ctx, clientset, "default")
+	newStatefulsets, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 	assert.NoError(t, err)
 	assert.Len(t, newStatefulsets.Items, 1)
 }
@@ -101,7 +101,7 @@ func TestDownscaleStatefulSets(t *testing.T) {
 			_, err := clientset.AppsV1().StatefulSets("default").Create(ctx, &tc.statefulset, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			statefulsets, err := GetStatefulSets(ctx, clientset, "default")
+			statefulsets, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			downscaledInfos, err := DownscaleStatefulSets(ctx, clientset, statefulsets, false)
@@ -109,7 +109,7 @@ func TestDownscaleStatefulSets(t *testing.T) {
 			scaledCount := countScaled(downscaledInfos)
 			assert.Equal(t, tc.expectedDownscaled, scaledCount)
 
-			newDeployments, err := GetStatefulSets(ctx, clientset, "default")
+			newDeployments, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDeployments.Items {
@@ -170,7 +170,7 @@ func TestUpscaleStatefulSets(t *testing.T) {
 			_, err := clientset.AppsV1().StatefulSets("default").Create(ctx, &tc.statefulset, metav1.CreateOptions{})
 			assert.NoError(t, err)
 
-			statefulsets, err := GetStatefulSets(ctx, clientset, "default")
+			statefulsets, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			upscaledInfos, err := UpscaleStatefulSets(ctx, clientset, statefulsets, false)
@@ -178,7 +178,7 @@ func TestUpscaleStatefulSets(t *testing.T) {
 			scaledCount := countScaled(upscaledInfos)
 			assert.Equal(t, tc.expectedUpscaled, scaledCount)
 
-			newDeployments, err := GetStatefulSets(ctx, clientset, "default")
+			newDeployments, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
 			assert.NoError(t, err)
 
 			for _, d := range newDeployments.Items {
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WorkloadStatus describes a workload currently left downscaled by szero
+type WorkloadStatus struct {
+	Kind           string `json:"kind"`
+	Name           string `json:"name"`
+	Replicas       *int32 `json:"replicas,omitempty"`       // replicas currently in the spec
+	StoredReplicas *int32 `json:"storedReplicas,omitempty"` // replicas `up` will restore
+	Detail         string `json:"detail,omitempty"`
+	Downscaled     *Audit `json:"downscaled,omitempty"` // who downscaled the workload, when, and why
+}
+
+// NamespaceStatus lists the workloads left downscaled by szero in a namespace
+type NamespaceStatus struct {
+	Namespace string           `json:"namespace"`
+	WakeUpAt  *time.Time       `json:"wakeUpAt,omitempty"` // when the namespace is scheduled to be brought back up
+	Workloads []WorkloadStatus `json:"workloads"`
+}
+
+// StatusReport is the document written by the structured status output
+type StatusReport struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Namespaces    []NamespaceStatus `json:"namespaces"`
+}
+
+// GetNamespaceStatus finds the workloads carrying szero's downscale state in a namespace, along with the
+// autoscalers and KEDA ScaledObjects left parked or paused for them, and the scalable resources given
+func GetNamespaceStatus(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, namespace string, scalableResources []ScalableResource, listOptions metav1.ListOptions) (NamespaceStatus, error) {
+	status := NamespaceStatus{Namespace: namespace, Workloads: []WorkloadStatus{}}
+
+	wakeUpAt, err := GetWakeUpDeadline(ctx, clientset, namespace)
+	if err != nil {
+		return status, err
+	}
+	status.WakeUpAt = wakeUpAt
+
+	deployments, err := GetDeployments(ctx, clientset, namespace, listOptions)
+	if err != nil {
+		return status, err
+	}
+	for _, d := range deployments.Items {
+		if stored, found := d.Annotations[replicasAnnotation]; found {
+			status.Workloads = append(status.Workloads, replicasStatus("Deployment", d.Name, d.Spec.Replicas, stored, d.Annotations))
+		}
+	}
+
+	statefulsets, err := GetStatefulSets(ctx, clientset, namespace, listOptions)
+	if err != nil {
+		return status, err
+	}
+	for _, s := range statefulsets.Items {
+		if stored, found := s.Annotations[replicasAnnotation]; found {
+			status.Workloads = append(status.Workloads, replicasStatus("StatefulSet", s.Name, s.Spec.Replicas, stored, s.Annotations))
+		}
+	}
+
+	daemonsets, err := GetDaemonsets(ctx, clientset, namespace, listOptions)
+	if err != nil {
+		return status, err
+	}
+	for _, d := range daemonsets.Items {
+		if _, found := d.Spec.Template.Spec.NodeSelector[noscheduleAnnotation]; found {
+			status.Workloads = append(status.Workloads, WorkloadStatus{Kind: "DaemonSet", Name: d.Name, Detail: noscheduleAnnotation + " node selector", Downscaled: auditFromAnnotations(d.Annotations)})
+		}
+	}
+
+	cronjobs, err := GetCronJobs(ctx, clientset, namespace, listOptions)
+	if err != nil {
+		return status, err
+	}
+	for _, c := range cronjobs.Items {
+		if _, found := c.Annotations[suspendAnnotation]; found {
+			status.Workloads = append(status.Workloads, WorkloadStatus{Kind: "CronJob", Name: c.Name, Detail: "suspended", Downscaled: auditFromAnnotations(c.Annotations)})
+		}
+	}
+
+	jobs, err := GetJobs(ctx, clientset, namespace, listOptions)
+	if err != nil {
+		return status, err
+	}
+	for _, j := range jobs.Items {
+		if _, found := j.Annotations[suspendAnnotation]; found {
+			status.Workloads = append(status.Workloads, WorkloadStatus{Kind: "Job", Name: j.Name, Detail: "suspended", Downscaled: auditFromAnnotations(j.Annotations)})
+		}
+	}
+
+	// Autoscalers and ScaledObjects follow the workloads they target, like on down
+	targets := map[string]bool{}
+	for _, d := range deployments.Items {
+		targets["Deployment/"+d.Name] = true
+	}
+	for _, s := range statefulsets.Items {
+		targets["StatefulSet/"+s.Name] = true
+	}
+
+	hpas, err := GetHorizontalPodAutoscalers(ctx, clientset, namespace)
+	if err != nil {
+		return status, err
+	}
+	for _, h := range hpas.Items {
+		if _, found := h.Annotations[hpaBehaviorAnnotation]; found && targets[hpaTarget(&h)] {
+			status.Workloads = append(status.Workloads, WorkloadStatus{Kind: "HorizontalPodAutoscaler", Name: h.Name, Detail: "parked, targets " + hpaTarget(&h), Downscaled: auditFromAnnotations(h.Annotations)})
+		}
+	}
+
+	scaledObjects, err := GetScaledObjects(ctx, dynamicClient, namespace)
+	if err != nil {
+		return status, err
+	}
+	for _, o := range ScaledObjectsFor(scaledObjects, deployments, statefulsets).Items {
+		if _, found := o.GetAnnotations()[kedaPausedAnnotation]; found {
+			kind, name := scaledObjectTarget(&o)
+			status.Workloads = append(status.Workloads, WorkloadStatus{Kind: "ScaledObject", Name: o.GetName(), Detail: "paused, targets " + kind + "/" + name, Downscaled: auditFromAnnotations(o.GetAnnotations())})
+		}
+	}
+
+	for _, resource := range scalableResources {
+		objects, err := GetScalableObjects(ctx, dynamicClient, namespace, resource, listOptions)
+		if err != nil {
+			return status, err
+		}
+		for _, o := range objects.Items {
+			if stored, found := o.GetAnnotations()[replicasAnnotation]; found {
+				var replicas *int32
+				if r, found, _ := unstructured.NestedInt64(o.Object, "spec", "replicas"); found {
+					replicas = int32Ptr(int(r))
+				}
+				status.Workloads = append(status.Workloads, replicasStatus(resource.Kind, o.GetName(), replicas, stored, o.GetAnnotations()))
+			}
+		}
+	}
+
+	return status, nil
+}
+
+func replicasStatus(kind string, name string, replicas *int32, stored string, annotations map[string]string) WorkloadStatus {
+	status := WorkloadStatus{Kind: kind, Name: name, Replicas: replicas, Downscaled: auditFromAnnotations(annotations)}
+	storedReplicas, err := strconv.ParseInt(stored, 10, 32)
+	if err != nil {
+		status.Detail = "invalid " + replicasAnnotation + " annotation"
+		return status
+	}
+	status.StoredReplicas = int32Ptr(int(storedReplicas))
+	if replicas != nil && *replicas > 0 {
+		status.Detail = "scaled up since downscale"
+	}
+	return status
+}
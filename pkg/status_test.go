@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNamespaceStatus(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{
+				replicasAnnotation:     "3",
+				downscaledAtAnnotation: "2025-01-02T15:04:05Z",
+				downscaledByAnnotation: "alice",
+				reasonAnnotation:       "release freeze",
+			}},
+			Spec: v1.DeploymentSpec{Replicas: int32Ptr(0)},
+		},
+		&v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "awake", Namespace: "default"},
+			Spec:       v1.DeploymentSpec{Replicas: int32Ptr(2)},
+		},
+		&v1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Annotations: map[string]string{replicasAnnotation: "2"}},
+			Spec:       v1.StatefulSetSpec{Replicas: int32Ptr(1)},
+		},
+		&v1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Spec: v1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				NodeSelector: map[string]string{noscheduleAnnotation: "true"},
+			}}},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", Annotations: map[string]string{suspendAnnotation: "false"}},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{
+			wakeUpAtAnnotation: "2025-01-04T15:05:00Z",
+		}}},
+	)
+
+	status, err := GetNamespaceStatus(ctx, clientset, newSnapshotDynamicClient(), "default", nil, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, NamespaceStatus{
+		Namespace: "default",
+		WakeUpAt:  new(time.Date(2025, 1, 4, 15, 5, 0, 0, time.UTC)),
+		Workloads: []WorkloadStatus{
+			{Kind: "Deployment", Name: "web", Replicas: int32Ptr(0), StoredReplicas: int32Ptr(3), Downscaled: &Audit{
+				At:     time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+				By:     "alice",
+				Reason: "release freeze",
+			}},
+			{Kind: "StatefulSet", Name: "db", Replicas: int32Ptr(1), StoredReplicas: int32Ptr(2), Detail: "scaled up since downscale"},
+			{Kind: "DaemonSet", Name: "agent", Detail: noscheduleAnnotation + " node selector"},
+			{Kind: "CronJob", Name: "backup", Detail: "suspended"},
+		},
+	}, status)
+
+	var out bytes.Buffer
+	assert.NoError(t, NewTreePrinterWithWriter(&out).PrintNamespaceStatus(status))
+	assert.Contains(t, out.String(), "Deployment/web 0 → 3 replicas")
+	assert.Contains(t, out.String(), "by alice at 2025-01-02T15:04:05Z: release freeze")
+	assert.Contains(t, out.String(), "DaemonSet/agent")
+	assert.Contains(t, out.String(), "wakes up at 2025-01-04T15:05:00Z")
+}
+
+func TestGetNamespaceStatusWithInvalidAnnotation(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{replicasAnnotation: "three"}},
+		Spec:       v1.DeploymentSpec{Replicas: int32Ptr(0)},
+	})
+
+	status, err := GetNamespaceStatus(ctx, clientset, newSnapshotDynamicClient(), "default", nil, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, status.Workloads, 1)
+	assert.Nil(t, status.Workloads[0].StoredReplicas)
+	assert.Equal(t, "invalid "+replicasAnnotation+" annotation", status.Workloads[0].Detail)
+}
+
+func TestGetNamespaceStatusOfControllingResources(t *testing.T) {
+	ctx := context.Background()
+	parked := newHorizontalPodAutoscaler("web", "Deployment", "web", map[string]string{hpaBehaviorAnnotation: "null"}, nil)
+	unrelated := newHorizontalPodAutoscaler("old", "Deployment", "gone", map[string]string{hpaBehaviorAnnotation: "null"}, nil)
+	clientset := testclient.NewClientset(
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(0)}},
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(0)}},
+		&parked,
+		&unrelated,
+	)
+	rollout := newRollout("canary", map[string]string{replicasAnnotation: "4"})
+	assert.NoError(t, unstructured.SetNestedField(rollout.Object, int64(0), "spec", "replicas"))
+	dynamicClient := newSnapshotDynamicClient(
+		newScaledObject("worker", "", "worker", map[string]string{kedaPausedAnnotation: "", kedaPausedReplicasAnnotation: "0"}),
+		newScaledObject("api", "", "api", nil),
+		rollout,
+	)
+
+	status, err := GetNamespaceStatus(ctx, clientset, dynamicClient, "default", []ScalableResource{rollouts}, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []WorkloadStatus{
+		{Kind: "HorizontalPodAutoscaler", Name: "web", Detail: "parked, targets Deployment/web"},
+		{Kind: "ScaledObject", Name: "worker", Detail: "paused, targets Deployment/worker"},
+		{Kind: "Rollout", Name: "canary", Replicas: int32Ptr(0), StoredReplicas: int32Ptr(4)},
+	}, status.Workloads)
+}
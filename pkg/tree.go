@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -15,25 +16,36 @@ type TreePrinter struct {
 
 // ScaleInfo contains information about a scaling operation
 type ScaleInfo struct {
-	Name     string
-	Replicas int32
-	Scaled   bool
-	Warning  string // if not scaled, this contains the reason
+	Name     string `json:"name"`
+	Replicas int32  `json:"replicas"`
+	Target   string `json:"target,omitempty"` // the workload this resource controls, e.g. "Deployment/web" for autoscalers
+	Scaled   bool   `json:"scaled"`
+	Excluded bool   `json:"excluded"`          // opted out of szero through the exclude annotation
+	Warning  string `json:"warning,omitempty"` // if not scaled, this contains the reason
+	Error    string `json:"error,omitempty"`   // set when scaling the resource failed
 }
 
 // ResourceGroup groups resources by type for tree output
 type ResourceGroup struct {
-	Type      string // "Deployments", "StatefulSets", "DaemonSets"
-	Resources []ScaleInfo
-	Skipped   bool
+	Type      string      `json:"type"` // "Deployments", "StatefulSets", "DaemonSets", "CronJobs", "Jobs", "HorizontalPodAutoscalers", "ScaledObjects"
+	Resources []ScaleInfo `json:"resources"`
+	Skipped   bool        `json:"skipped"`
+	Error     string      `json:"error,omitempty"` // set when the resources could not be listed
 }
 
 // NamespaceResult contains all scaling results for a namespace
 type NamespaceResult struct {
-	Namespace    string
-	Deployments  ResourceGroup
-	StatefulSets ResourceGroup
-	DaemonSets   ResourceGroup
+	Namespace     string          `json:"namespace"`
+	Excluded      bool            `json:"excluded"`        // the namespace opted out of szero and was left untouched
+	Audit         *Audit          `json:"audit,omitempty"` // who downscaled the namespace, when, and why
+	Deployments   ResourceGroup   `json:"deployments"`
+	StatefulSets  ResourceGroup   `json:"statefulSets"`
+	DaemonSets    ResourceGroup   `json:"daemonSets"`
+	CronJobs      ResourceGroup   `json:"cronJobs"`
+	Jobs          ResourceGroup   `json:"jobs"`
+	HPAs          ResourceGroup   `json:"horizontalPodAutoscalers"`
+	ScaledObjects ResourceGroup   `json:"scaledObjects"`
+	Scalables     []ResourceGroup `json:"scalables"` // one group per discovered resource exposing the scale subresource
 }
 
 var (
@@ -42,9 +54,15 @@ var (
 	itemStyle      = lipgloss.NewStyle() // Use default terminal color for visibility on both themes
 	replicaStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
 	warnStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	errorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 	skipStyle      = lipgloss.NewStyle().Italic(true) // Use default color with italic for visibility
 )
 
+// Flush does nothing, since the tree is printed as results come in
+func (tp *TreePrinter) Flush() error {
+	return nil
+}
+
 // NewTreePrinter creates a new TreePrinter
 func NewTreePrinter() *TreePrinter {
 	return &TreePrinter{writer: os.Stdout}
@@ -58,11 +76,24 @@ func NewTreePrinterWithWriter(w io.Writer) *TreePrinter {
 // PrintNamespaceResult prints the scaling result for a namespace in tree format
 func (tp *TreePrinter) PrintNamespaceResult(result NamespaceResult) error {
 	// Print namespace header
-	if _, err := fmt.Fprintf(tp.writer, "%s\n", namespaceStyle.Render(result.Namespace)); err != nil {
+	header := namespaceStyle.Render(result.Namespace)
+	if result.Audit != nil {
+		header = fmt.Sprintf("%s %s", header, skipStyle.Render(result.Audit.String()))
+	}
+	if _, err := fmt.Fprintf(tp.writer, "%s\n", header); err != nil {
+		return err
+	}
+
+	if result.Excluded {
+		if _, err := fmt.Fprintf(tp.writer, "└── %s\n", skipStyle.Render(fmt.Sprintf("(%s)", ExcludedReason))); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(tp.writer)
 		return err
 	}
 
-	groups := []ResourceGroup{result.Deployments, result.StatefulSets, result.DaemonSets}
+	groups := []ResourceGroup{result.Deployments, result.StatefulSets, result.DaemonSets, result.CronJobs, result.Jobs, result.HPAs, result.ScaledObjects}
+	groups = append(groups, result.Scalables...)
 
 	for i, group := range groups {
 		isLast := i == len(groups)-1
@@ -87,6 +118,11 @@ func (tp *TreePrinter) printResourceGroup(group ResourceGroup, isLast bool) erro
 		return err
 	}
 
+	if group.Error != "" {
+		_, err := fmt.Fprintf(tp.writer, "%s%s %s\n", connector, resourceStyle.Render(group.Type), errorStyle.Render(fmt.Sprintf("(error: %s)", group.Error)))
+		return err
+	}
+
 	// Print resource type header with count
 	scaledCount := 0
 	for _, r := range group.Resources {
@@ -107,18 +143,28 @@ func (tp *TreePrinter) printResourceGroup(group ResourceGroup, isLast bool) erro
 			itemConnector = "└── "
 		}
 
+		name := res.Name
+		if res.Target != "" {
+			name = fmt.Sprintf("%s (%s)", res.Name, res.Target)
+		}
+
 		if res.Scaled {
 			var info string
 			if res.Replicas > 0 {
-				info = fmt.Sprintf("%s → %s", res.Name, replicaStyle.Render(fmt.Sprintf("%d replicas", res.Replicas)))
+				info = fmt.Sprintf("%s → %s", name, replicaStyle.Render(fmt.Sprintf("%d replicas", res.Replicas)))
 			} else {
-				info = res.Name
+				info = name
+			}
+			if _, err := fmt.Fprintf(tp.writer, "%s%s%s\n", childPrefix, itemConnector, itemStyle.Render(info)); err != nil {
+				return err
 			}
+		} else if res.Error != "" {
+			info := fmt.Sprintf("%s %s", name, errorStyle.Render(fmt.Sprintf("(error: %s)", res.Error)))
 			if _, err := fmt.Fprintf(tp.writer, "%s%s%s\n", childPrefix, itemConnector, itemStyle.Render(info)); err != nil {
 				return err
 			}
 		} else {
-			info := fmt.Sprintf("%s %s", res.Name, warnStyle.Render(fmt.Sprintf("(%s)", res.Warning)))
+			info := fmt.Sprintf("%s %s", name, warnStyle.Render(fmt.Sprintf("(%s)", res.Warning)))
 			if _, err := fmt.Fprintf(tp.writer, "%s%s%s\n", childPrefix, itemConnector, itemStyle.Render(info)); err != nil {
 				return err
 			}
@@ -126,3 +172,72 @@ func (tp *TreePrinter) printResourceGroup(group ResourceGroup, isLast bool) erro
 	}
 	return nil
 }
+
+// PrintNamespaceStatus prints the workloads left downscaled by szero in a namespace in tree format
+func (tp *TreePrinter) PrintNamespaceStatus(status NamespaceStatus) error {
+	header := namespaceStyle.Render(status.Namespace)
+	if status.WakeUpAt != nil {
+		header = fmt.Sprintf("%s %s", header, skipStyle.Render("wakes up at "+status.WakeUpAt.Format(time.RFC3339)))
+	}
+	if _, err := fmt.Fprintf(tp.writer, "%s\n", header); err != nil {
+		return err
+	}
+
+	for i, w := range status.Workloads {
+		connector := "├── "
+		if i == len(status.Workloads)-1 {
+			connector = "└── "
+		}
+
+		info := fmt.Sprintf("%s/%s", w.Kind, w.Name)
+		if w.StoredReplicas != nil {
+			current := int32(0)
+			if w.Replicas != nil {
+				current = *w.Replicas
+			}
+			info = fmt.Sprintf("%s %d → %s", info, current, replicaStyle.Render(fmt.Sprintf("%d replicas", *w.StoredReplicas)))
+		}
+		if w.Detail != "" {
+			info = fmt.Sprintf("%s %s", info, warnStyle.Render(fmt.Sprintf("(%s)", w.Detail)))
+		}
+		if w.Downscaled != nil {
+			info = fmt.Sprintf("%s %s", info, skipStyle.Render(w.Downscaled.String()))
+		}
+		if _, err := fmt.Fprintf(tp.writer, "%s%s\n", connector, itemStyle.Render(info)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(tp.writer)
+	return err
+}
+
+// PrintSchedule prints the sleep schedule of a namespace in tree format
+func (tp *TreePrinter) PrintSchedule(schedule SleepSchedule) error {
+	header := fmt.Sprintf("%s %s", namespaceStyle.Render(schedule.Namespace), skipStyle.Render(fmt.Sprintf("(%s, %s)", schedule.TimeZone, schedule.Image)))
+	if _, err := fmt.Fprintf(tp.writer, "%s\n", header); err != nil {
+		return err
+	}
+	lines := []struct {
+		connector string
+		operation string
+		cron      string
+		lastRun   *time.Time
+	}{
+		{"├── ", "down", schedule.Down, schedule.LastDown},
+		{"└── ", "up", schedule.Up, schedule.LastUp},
+	}
+	for _, line := range lines {
+		info := fmt.Sprintf("%s %s", line.operation, replicaStyle.Render(line.cron))
+		if line.cron == "" {
+			info = fmt.Sprintf("%s %s", line.operation, warnStyle.Render("(missing)"))
+		}
+		if line.lastRun != nil {
+			info = fmt.Sprintf("%s %s", info, skipStyle.Render("last ran at "+line.lastRun.Format(time.RFC3339)))
+		}
+		if _, err := fmt.Fprintf(tp.writer, "%s%s\n", line.connector, itemStyle.Render(info)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(tp.writer)
+	return err
+}
@@ -0,0 +1,325 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// WaitOptions tell what state to wait for the workloads to reach, and for how long
+type WaitOptions struct {
+	Timeout    time.Duration
+	Downscaled bool     // wait for the workloads to be down rather than back up
+	FailOn     []string // pod problem reasons ending the wait for workloads coming back up right away, e.g. "ImagePullBackOff"
+	PodsGone   bool     // when downscaled, also wait for every pod of the workloads to be gone, Terminating ones included
+}
+
+// waitedObject is a workload szero waits for, like *v1.Deployment
+type waitedObject interface {
+	runtime.Object
+	metav1.Object
+}
+
+// waitedKind tells how to watch a kind of workload and when it reached the desired state
+type waitedKind[T waitedObject] struct {
+	resource string // e.g. "deployments"
+	kind     string // e.g. "Deployment"
+	objType  T
+	list     cache.ListWithContextFunc
+	watch    cache.WatchFuncWithContext
+	ready    func(T) bool
+	replicas func(T) (current int32, desired int32) // the replicas ready compares
+	selector func(T) *metav1.LabelSelector          // the pods of the workload
+}
+
+// WaitTimeoutError reports the workloads of a kind that did not reach the desired state in time
+type WaitTimeoutError struct {
+	Resource  string            `json:"resource"`
+	Namespace string            `json:"namespace"`
+	NotReady  []UnreadyWorkload `json:"notReady"`
+}
+
+func (e *WaitTimeoutError) Error() string {
+	if len(e.NotReady) == 0 {
+		return fmt.Sprintf("timeout waiting for %s to reconcile", e.Resource)
+	}
+	names := make([]string, len(e.NotReady))
+	for i, w := range e.NotReady {
+		names[i] = w.Name
+	}
+	return fmt.Sprintf("timeout waiting for %s to reconcile, not ready: %s", e.Resource, strings.Join(names, ", "))
+}
+
+// PodFailureError reports a pod keeping its workload from becoming ready, in a state it does not recover from by itself
+type PodFailureError struct {
+	Namespace string     `json:"namespace"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Problem   PodProblem `json:"problem"`
+}
+
+func (e *PodFailureError) Error() string {
+	message := fmt.Sprintf("%s %s will not become ready, pod %s is in %s", e.Kind, e.Name, e.Problem.Pod, e.Problem.Reason)
+	if e.Problem.Container != "" {
+		message += fmt.Sprintf(" (container %s, image %s)", e.Problem.Container, e.Problem.Image)
+	}
+	if e.Problem.Message != "" {
+		message += ": " + e.Problem.Message
+	}
+	return message
+}
+
+// UnreadyWorkload is a workload that did not reach the desired state, along with what its pods are going through
+type UnreadyWorkload struct {
+	Kind        string       `json:"kind"`
+	Name        string       `json:"name"`
+	Replicas    int32        `json:"replicas"`
+	Desired     int32        `json:"desired"`
+	PodProblems []PodProblem `json:"podProblems,omitempty"`
+}
+
+// waitUntilReady lists the objects of a kind in a namespace once and then watches them, until every one of
+// objects is ready. Objects deleted while waiting are no longer waited for. On timeout, a *WaitTimeoutError
+// tells which objects are not ready and why. When waiting for the objects to come back up, a pod of theirs
+// running into one of the FailOn reasons ends the wait right away with a *PodFailureError. When waiting for
+// them to be down with PodsGone, their pods are waited for as well once the objects are ready.
+func waitUntilReady[T waitedObject](ctx context.Context, clientset kubernetes.Interface, namespace string, waited waitedKind[T], objects []T, options WaitOptions) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	names := make([]string, len(objects))
+	for i, o := range objects {
+		names[i] = o.GetName()
+	}
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, options.Timeout)
+	defer cancelTimeout()
+	// Errors retrying won't fix end the wait right away, the others are retried until the timeout
+	watchCtx, cancel := context.WithCancelCause(timeoutCtx)
+	defer cancel(nil)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+			objects, err := waited.list(ctx, listOptions)
+			if isPermanent(err) {
+				cancel(fmt.Errorf("error getting %s: %w", waited.resource, err))
+			}
+			return objects, err
+		},
+		WatchFuncWithContext: func(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error) {
+			watcher, err := waited.watch(ctx, listOptions)
+			if isPermanent(err) {
+				cancel(fmt.Errorf("error watching %s: %w", waited.resource, err))
+			}
+			return watcher, err
+		},
+	}
+
+	if !options.Downscaled && len(options.FailOn) > 0 {
+		selectors := podSelectors(waited, objects)
+		podsWatched := make(chan struct{})
+		go func() {
+			defer close(podsWatched)
+			watchPodFailures(watchCtx, clientset, namespace, waited.kind, selectors, options.FailOn, cancel)
+		}()
+		defer func() {
+			cancel(nil)
+			<-podsWatched
+		}()
+	}
+
+	// The latest state of the objects, and of the ones that are not ready yet
+	latest := make(map[string]T, len(names))
+	for _, o := range objects {
+		latest[o.GetName()] = o
+	}
+	pending := make(map[string]T, len(names))
+	update := func(object T, deleted bool) {
+		if !slices.Contains(names, object.GetName()) {
+			return
+		}
+		latest[object.GetName()] = object
+		if deleted || waited.ready(object) {
+			delete(pending, object.GetName())
+		} else {
+			pending[object.GetName()] = object
+		}
+	}
+
+	_, err := watchtools.UntilWithSync(watchCtx, cache.ToListWatcherWithWatchListSemantics(lw, clientset), waited.objType,
+		func(store cache.Store) (bool, error) {
+			for _, o := range store.List() {
+				if object, ok := o.(T); ok {
+					update(object, false)
+				}
+			}
+			return len(pending) == 0, nil
+		},
+		func(event watch.Event) (bool, error) {
+			if object, ok := event.Object.(T); ok {
+				update(object, event.Type == watch.Deleted)
+			}
+			return len(pending) == 0, nil
+		},
+	)
+	if err == nil && options.Downscaled && options.PodsGone {
+		maps.Copy(pending, latest)
+		err = waitUntilPodsGone(watchCtx, clientset, namespace, podSelectors(waited, objects), pending, cancel)
+	}
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(watchCtx); cause != nil && !errors.Is(cause, context.DeadlineExceeded) && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	if !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	timeoutErr := &WaitTimeoutError{Resource: waited.resource, Namespace: namespace}
+	for _, name := range slices.Sorted(maps.Keys(pending)) {
+		workload := UnreadyWorkload{Kind: waited.kind, Name: name}
+		workload.Replicas, workload.Desired = waited.replicas(pending[name])
+		// Finding out why is best effort, the timeout is what matters
+		workload.PodProblems, _ = GetPodProblems(ctx, clientset, namespace, waited.selector(pending[name]))
+		timeoutErr.NotReady = append(timeoutErr.NotReady, workload)
+	}
+	return timeoutErr
+}
+
+// isPermanent reports whether listing or watching failed in a way retrying won't fix, like being forbidden.
+// Other errors are retried by the reflector.
+func isPermanent(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsNotFound(err)
+}
+
+// podSelectors maps the name of each object to the selector of its pods, leaving out unparsable selectors
+func podSelectors[T waitedObject](waited waitedKind[T], objects []T) map[string]labels.Selector {
+	selectors := make(map[string]labels.Selector, len(objects))
+	for _, o := range objects {
+		if selector, err := metav1.LabelSelectorAsSelector(waited.selector(o)); err == nil {
+			selectors[o.GetName()] = selector
+		}
+	}
+	return selectors
+}
+
+// waitUntilPodsGone watches the pods in a namespace until none of the pending objects has pods left, going by
+// their selectors, and removes the objects whose pods are all gone from pending. Errors listing or watching the pods
+// that retrying won't fix fail the wait.
+func waitUntilPodsGone[T waitedObject](ctx context.Context, clientset kubernetes.Interface, namespace string, selectors map[string]labels.Selector, pending map[string]T, fail context.CancelCauseFunc) error {
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			pods, err := clientset.CoreV1().Pods(namespace).List(ctx, options)
+			if isPermanent(err) {
+				fail(fmt.Errorf("error getting pods: %w", err))
+			}
+			return pods, err
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			watcher, err := clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+			if isPermanent(err) {
+				fail(fmt.Errorf("error watching pods: %w", err))
+			}
+			return watcher, err
+		},
+	}
+
+	// The labels of the pods that are still around, by name
+	pods := make(map[string]labels.Set)
+	gone := func() bool {
+		for name := range pending {
+			remaining := false
+			if selector, ok := selectors[name]; ok {
+				for _, podLabels := range pods {
+					remaining = remaining || selector.Matches(podLabels)
+				}
+			}
+			if !remaining {
+				delete(pending, name)
+			}
+		}
+		return len(pending) == 0
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, cache.ToListWatcherWithWatchListSemantics(lw, clientset), &corev1.Pod{},
+		func(store cache.Store) (bool, error) {
+			for _, o := range store.List() {
+				if pod, ok := o.(*corev1.Pod); ok {
+					pods[pod.Name] = pod.Labels
+				}
+			}
+			return gone(), nil
+		},
+		func(event watch.Event) (bool, error) {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				return false, nil
+			}
+			if event.Type == watch.Deleted {
+				delete(pods, pod.Name)
+			} else {
+				pods[pod.Name] = pod.Labels
+			}
+			return gone(), nil
+		},
+	)
+	return err
+}
+
+// watchPodFailures watches the pods in a namespace until one of the workloads with the given selectors has a
+// pod running into one of the failOn reasons, and fails the wait with it. Pods that cannot be listed or
+// watched for good are not looked at.
+func watchPodFailures(ctx context.Context, clientset kubernetes.Interface, namespace string, kind string, selectors map[string]labels.Selector, failOn []string, fail context.CancelCauseFunc) {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			pods, err := clientset.CoreV1().Pods(namespace).List(ctx, options)
+			if isPermanent(err) {
+				stop()
+			}
+			return pods, err
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			watcher, err := clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+			if isPermanent(err) {
+				stop()
+			}
+			return watcher, err
+		},
+	}
+
+	_, _ = watchtools.UntilWithSync(ctx, cache.ToListWatcherWithWatchListSemantics(lw, clientset), &corev1.Pod{}, nil,
+		func(event watch.Event) (bool, error) {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok || event.Type == watch.Deleted {
+				return false, nil
+			}
+			problem, found := podProblem(pod)
+			if !found || !slices.Contains(failOn, problem.Reason) {
+				return false, nil
+			}
+			for name, selector := range selectors {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					fail(&PodFailureError{Namespace: namespace, Kind: kind, Name: name, Problem: problem})
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	)
+}
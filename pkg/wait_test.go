@@ -0,0 +1,300 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newWaitedDeployment(name string, replicas int32, available int32, annotations map[string]string) *v1.Deployment {
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+		Spec:       v1.DeploymentSpec{Replicas: new(replicas)},
+		Status:     v1.DeploymentStatus{Replicas: available, ReadyReplicas: available, AvailableReplicas: available},
+	}
+}
+
+func TestWaitForDeployments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("When the deployments become ready then waiting ends", func(t *testing.T) {
+		clientset := testclient.NewClientset(
+			newWaitedDeployment("web", 2, 0, nil),
+			newWaitedDeployment("api", 1, 1, nil),
+		)
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		waited := make(chan error)
+		go func() { waited <- WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Minute}) }()
+
+		time.Sleep(100 * time.Millisecond)
+		_, err = clientset.AppsV1().Deployments("default").UpdateStatus(ctx, newWaitedDeployment("web", 2, 2, nil), metav1.UpdateOptions{})
+		assert.NoError(t, err)
+		select {
+		case err := <-waited:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("waiting did not end when the deployments became ready")
+		}
+	})
+
+	t.Run("When a deployment is deleted then it is no longer waited for", func(t *testing.T) {
+		clientset := testclient.NewClientset(newWaitedDeployment("web", 0, 2, nil))
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		waited := make(chan error)
+		go func() {
+			waited <- WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Minute, Downscaled: true})
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, clientset.AppsV1().Deployments("default").Delete(ctx, "web", metav1.DeleteOptions{}))
+		select {
+		case err := <-waited:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("waiting did not end when the deployment was deleted")
+		}
+	})
+
+	t.Run("When only excluded deployments are not ready then there is nothing to wait for", func(t *testing.T) {
+		clientset := testclient.NewClientset(
+			newWaitedDeployment("web", 2, 2, nil),
+			newWaitedDeployment("db", 1, 0, map[string]string{excludeAnnotation: "true"}),
+		)
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Second}))
+	})
+
+	t.Run("When the deployments never become ready then waiting times out telling why", func(t *testing.T) {
+		web := newWaitedDeployment("web", 2, 1, nil)
+		web.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+		clientset := testclient.NewClientset(web, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "web",
+					Image: "registry.example.com/web:1.2",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+				}},
+			},
+		})
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: 200 * time.Millisecond})
+		assert.EqualError(t, err, "timeout waiting for deployments to reconcile, not ready: web")
+		var timeoutErr *WaitTimeoutError
+		assert.ErrorAs(t, err, &timeoutErr)
+		assert.Equal(t, []UnreadyWorkload{{
+			Kind:     "Deployment",
+			Name:     "web",
+			Replicas: 1,
+			Desired:  2,
+			PodProblems: []PodProblem{
+				{Pod: "web-1", Container: "web", Image: "registry.example.com/web:1.2", Reason: "ImagePullBackOff", Message: "Back-off pulling image"},
+			},
+		}}, timeoutErr.NotReady)
+	})
+
+	t.Run("When listing the deployments is forbidden then waiting fails right away", func(t *testing.T) {
+		clientset := testclient.NewClientset(newWaitedDeployment("web", 2, 1, nil))
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+		clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "", errors.New("no access"))
+		})
+
+		start := time.Now()
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Minute})
+		assert.ErrorContains(t, err, "error getting deployments: ")
+		assert.True(t, apierrors.IsForbidden(err))
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+
+	t.Run("When listing the deployments fails for a while then waiting retries", func(t *testing.T) {
+		clientset := testclient.NewClientset(newWaitedDeployment("web", 2, 2, nil))
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+		failures := 0
+		clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if failures < 1 {
+				failures++
+				return true, nil, apierrors.NewServiceUnavailable("etcd is restarting")
+			}
+			return false, nil, nil
+		})
+
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: 10 * time.Second})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, failures)
+	})
+}
+
+func TestWaitForStatefulSetsAndDaemonSets(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&v1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       v1.StatefulSetSpec{Replicas: new(int32(0))},
+		},
+		&v1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Status:     v1.DaemonSetStatus{NumberReady: 1},
+		},
+	)
+
+	statefulsets, err := GetStatefulSets(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, WaitForStatefulSets(ctx, clientset, statefulsets, WaitOptions{Timeout: time.Second, Downscaled: true}))
+
+	daemonsets, err := GetDaemonsets(ctx, clientset, "default", metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.EqualError(t, WaitForDaemonSets(ctx, clientset, daemonsets, WaitOptions{Timeout: 200 * time.Millisecond, Downscaled: true}), "timeout waiting for daemonsets to reconcile, not ready: agent")
+}
+
+func TestWaitForDeploymentsFailsOnPodProblems(t *testing.T) {
+	ctx := context.Background()
+	web := newWaitedDeployment("web", 2, 0, nil)
+	web.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	pullingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "web",
+				Image: "registry.example.com/web:1.2",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+			}},
+		},
+	}
+
+	t.Run("When a pod runs into one of the reasons then waiting fails right away", func(t *testing.T) {
+		clientset := testclient.NewClientset(web.DeepCopy())
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		waited := make(chan error)
+		go func() {
+			waited <- WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Minute, FailOn: []string{"ImagePullBackOff"}})
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		_, err = clientset.CoreV1().Pods("default").Create(ctx, pullingPod.DeepCopy(), metav1.CreateOptions{})
+		assert.NoError(t, err)
+		select {
+		case err := <-waited:
+			assert.EqualError(t, err, "Deployment web will not become ready, pod web-1 is in ImagePullBackOff (container web, image registry.example.com/web:1.2): Back-off pulling image")
+			var failure *PodFailureError
+			assert.ErrorAs(t, err, &failure)
+		case <-time.After(10 * time.Second):
+			t.Fatal("waiting did not fail when a pod could not pull its image")
+		}
+	})
+
+	t.Run("When pod problems are not among the reasons then waiting goes on", func(t *testing.T) {
+		clientset := testclient.NewClientset(web.DeepCopy(), pullingPod.DeepCopy())
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: 300 * time.Millisecond, FailOn: []string{"CrashLoopBackOff"}})
+		var timeoutErr *WaitTimeoutError
+		assert.ErrorAs(t, err, &timeoutErr)
+	})
+
+	t.Run("When pods of other workloads run into the reasons then waiting goes on", func(t *testing.T) {
+		otherPod := pullingPod.DeepCopy()
+		otherPod.Labels = map[string]string{"app": "api"}
+		clientset := testclient.NewClientset(web.DeepCopy(), otherPod)
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: 300 * time.Millisecond, FailOn: []string{"ImagePullBackOff"}})
+		var timeoutErr *WaitTimeoutError
+		assert.ErrorAs(t, err, &timeoutErr)
+	})
+}
+
+func TestWaitForDeploymentsPodsGone(t *testing.T) {
+	ctx := context.Background()
+	web := newWaitedDeployment("web", 0, 0, nil)
+	web.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	terminatingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "web-1",
+			Namespace:         "default",
+			Labels:            map[string]string{"app": "web"},
+			DeletionTimestamp: new(metav1.Now()),
+			Finalizers:        []string{"example.com/hold"},
+		},
+	}
+
+	t.Run("When pods are not waited for then a downscaled deployment is enough", func(t *testing.T) {
+		clientset := testclient.NewClientset(web.DeepCopy(), terminatingPod.DeepCopy())
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Second, Downscaled: true}))
+	})
+
+	t.Run("When a pod is still terminating then waiting goes on until it is gone", func(t *testing.T) {
+		clientset := testclient.NewClientset(web.DeepCopy(), terminatingPod.DeepCopy())
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+
+		waited := make(chan error)
+		go func() {
+			waited <- WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: time.Minute, Downscaled: true, PodsGone: true})
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		select {
+		case err := <-waited:
+			t.Fatalf("waiting ended while a pod was terminating: %v", err)
+		default:
+		}
+		assert.NoError(t, clientset.CoreV1().Pods("default").Delete(ctx, "web-1", metav1.DeleteOptions{}))
+		select {
+		case err := <-waited:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("waiting did not end when the pod was gone")
+		}
+	})
+
+	t.Run("When a pod never goes away then waiting times out telling which", func(t *testing.T) {
+		otherPod := terminatingPod.DeepCopy()
+		otherPod.Name, otherPod.Labels = "api-1", map[string]string{"app": "api"}
+		running := web.DeepCopy()
+		running.Spec.Replicas, running.Status = new(int32(3)), v1.DeploymentStatus{Replicas: 3, ReadyReplicas: 3, AvailableReplicas: 3}
+		clientset := testclient.NewClientset(running, terminatingPod.DeepCopy(), otherPod)
+		// The deployments as they were before being scaled down
+		deployments, err := GetDeployments(ctx, clientset, "default", metav1.ListOptions{})
+		assert.NoError(t, err)
+		_, err = clientset.AppsV1().Deployments("default").Update(ctx, web.DeepCopy(), metav1.UpdateOptions{})
+		assert.NoError(t, err)
+
+		err = WaitForDeployments(ctx, clientset, deployments, WaitOptions{Timeout: 300 * time.Millisecond, Downscaled: true, PodsGone: true})
+		var timeoutErr *WaitTimeoutError
+		assert.ErrorAs(t, err, &timeoutErr)
+		assert.Equal(t, []UnreadyWorkload{{
+			Kind:        "Deployment",
+			Name:        "web",
+			PodProblems: []PodProblem{{Pod: "web-1", Reason: "Terminating"}},
+		}}, timeoutErr.NotReady)
+	})
+}
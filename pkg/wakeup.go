@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WakeUpName is the name of the CronJob, ServiceAccount, Role and RoleBinding bringing a namespace back up
+const WakeUpName = "szero-wake-up"
+
+// wakeUpAtAnnotation is set on a namespace to the time it is scheduled to be brought back up
+const wakeUpAtAnnotation = "szero/wake-up-at"
+
+// wakeUpRules allow the wake-up job to bring a namespace back up with the selected features, to make its own
+// CronJob retry every hour, and to clear its deadline and delete its own CronJob afterwards. Everything else created for the wake-up is owned by the CronJob
+// and removed along with it.
+func wakeUpRules(features Features) []rbacv1.PolicyRule {
+	return append(RequiredRules(features),
+		rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"patch"}},
+		rbacv1.PolicyRule{APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}, ResourceNames: []string{WakeUpName}, Verbs: []string{"get", "patch", "delete"}},
+	)
+}
+
+// WakeUp describes the job bringing a namespace back up at a deadline
+type WakeUp struct {
+	Namespace string
+	At        time.Time
+	Image     string
+	Args      []string // arguments for the szero binary in the image
+	Features  Features // what the job does when bringing the namespace back up
+}
+
+// CreateWakeUp creates a CronJob running szero in the namespace when the deadline passes, replacing any previous one,
+// along with the ServiceAccount, Role and RoleBinding it runs with, and records the deadline on the namespace
+func CreateWakeUp(ctx context.Context, clientset kubernetes.Interface, wakeUp WakeUp, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+
+	at := wakeUp.At.UTC()
+	// The job runs once at the deadline, and switches to running every hour until up deletes the CronJob
+	schedule := fmt.Sprintf("%d %d %d %d *", at.Minute(), at.Hour(), at.Day(), at.Month())
+	cronJob := inClusterCronJob(WakeUpName, wakeUp.Namespace, schedule, "Etc/UTC", wakeUp.Image, WakeUpName, wakeUp.Args)
+	cronJob, err := applyCronJob(ctx, clientset, cronJob)
+	if err != nil {
+		return fmt.Errorf("error creating wake-up cronjob: %w", err)
+	}
+
+	serviceAccount, role, roleBinding := inClusterRBAC(WakeUpName, wakeUp.Namespace, wakeUpRules(wakeUp.Features))
+	owner := []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+	serviceAccount.OwnerReferences = owner
+	role.OwnerReferences = owner
+	roleBinding.OwnerReferences = owner
+	if err := applyRBAC(ctx, clientset, serviceAccount, role, roleBinding); err != nil {
+		return fmt.Errorf("error creating wake-up permissions: %w", err)
+	}
+
+	deadline := at.Format(time.RFC3339)
+	if err := setWakeUpDeadline(ctx, clientset, wakeUp.Namespace, &deadline); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RetryWakeUpHourly makes the wake-up job of a namespace run every hour from the deadline on. The job does so before
+// bringing the namespace up, so that it keeps retrying when that fails, until up deletes the CronJob.
+// CronJobs szero did not create are left alone.
+func RetryWakeUpHourly(ctx context.Context, clientset kubernetes.Interface, namespace string, at time.Time) error {
+	cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, WakeUpName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting wake-up cronjob: %w", err)
+	}
+	schedule := fmt.Sprintf("%d * * * *", at.UTC().Minute())
+	if !isManaged(cronJob) || cronJob.Spec.Schedule == schedule {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{"spec": map[string]any{"schedule": schedule}})
+	if err != nil {
+		return err
+	}
+	if _, err := clientset.BatchV1().CronJobs(namespace).Patch(ctx, WakeUpName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error rescheduling wake-up cronjob: %w", err)
+	}
+	return nil
+}
+
+// GetWakeUpDeadline returns when the namespace is scheduled to be brought back up, or nil if it is not
+func GetWakeUpDeadline(ctx context.Context, clientset kubernetes.Interface, namespace string) (*time.Time, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting namespace %s: %w", namespace, err)
+	}
+	value, found := ns.Annotations[wakeUpAtAnnotation]
+	if !found {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing wake-up deadline: %w", err)
+	}
+	return &at, nil
+}
+
+// setWakeUpDeadline records the deadline on the namespace, or clears it when nil
+func setWakeUpDeadline(ctx context.Context, clientset kubernetes.Interface, namespace string, deadline *string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]*string{wakeUpAtAnnotation: deadline}},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error recording wake-up deadline: %w", err)
+	}
+	return nil
+}
+
+// DeleteWakeUp removes the scheduled wake-up of a namespace, if there is one.
+// Deleting the CronJob removes the ServiceAccount, Role and RoleBinding it owns as well.
+// CronJobs szero did not create are left alone.
+func DeleteWakeUp(ctx context.Context, clientset kubernetes.Interface, namespace string, dryRun bool) (bool, error) {
+	at, err := GetWakeUpDeadline(ctx, clientset, namespace)
+	if err != nil {
+		return false, err
+	}
+	cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, WakeUpName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("error getting wake-up cronjob: %w", err)
+	}
+	scheduled := err == nil && isManaged(cronJob)
+	if at == nil && !scheduled {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	// The deadline goes first, the wake-up job loses its permissions along with the CronJob
+	if at != nil {
+		if err := setWakeUpDeadline(ctx, clientset, namespace, nil); err != nil {
+			return false, err
+		}
+	}
+	if scheduled {
+		err = clientset.BatchV1().CronJobs(namespace).Delete(ctx, WakeUpName, metav1.DeleteOptions{
+			PropagationPolicy: new(metav1.DeletePropagationBackground),
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("error deleting wake-up cronjob: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateWakeUp(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	wakeUp := WakeUp{
+		Namespace: "default",
+		At:        time.Date(2025, 3, 7, 18, 30, 0, 0, time.UTC),
+		Image:     "ghcr.io/example/szero:latest",
+		Args:      []string{"wake-up", "--namespace=default", "--scale-subresources"},
+		Features:  Features{Deployments: true, Scalables: []ScalableResource{rollouts}},
+	}
+
+	assert.NoError(t, CreateWakeUp(ctx, clientset, wakeUp, true))
+	_, err := clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.Error(t, err)
+	at, err := GetWakeUpDeadline(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Nil(t, at)
+
+	assert.NoError(t, CreateWakeUp(ctx, clientset, wakeUp, false))
+	cronJob, err := clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "30 18 7 3 *", cronJob.Spec.Schedule)
+	assert.Equal(t, "Etc/UTC", *cronJob.Spec.TimeZone)
+	assert.True(t, IsExcluded(cronJob))
+	container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, wakeUp.Image, container.Image)
+	assert.Equal(t, wakeUp.Args, container.Args)
+	assert.Equal(t, WakeUpName, cronJob.Spec.JobTemplate.Spec.Template.Spec.ServiceAccountName)
+
+	serviceAccount, err := clientset.CoreV1().ServiceAccounts("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "CronJob", serviceAccount.OwnerReferences[0].Kind)
+	role, err := clientset.RbacV1().Roles("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, wakeUpRules(wakeUp.Features), role.Rules)
+	assert.Contains(t, role.Rules, rbacv1.PolicyRule{APIGroups: []string{"argoproj.io"}, Resources: []string{"rollouts/scale"}, Verbs: []string{"get", "update"}})
+	roleBinding, err := clientset.RbacV1().RoleBindings("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, WakeUpName, roleBinding.Subjects[0].Name)
+
+	at, err = GetWakeUpDeadline(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, wakeUp.At, *at)
+
+	// Once due, the job retries every hour
+	assert.NoError(t, RetryWakeUpHourly(ctx, clientset, "default", *at))
+	cronJob, err = clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "30 * * * *", cronJob.Spec.Schedule)
+
+	// Downscaling again moves the deadline
+	wakeUp.At = wakeUp.At.Add(24 * time.Hour)
+	assert.NoError(t, CreateWakeUp(ctx, clientset, wakeUp, false))
+	at, err = GetWakeUpDeadline(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, wakeUp.At, *at)
+	cronJob, err = clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "30 18 8 3 *", cronJob.Spec.Schedule)
+}
+
+func TestCreateWakeUpKeepsForeignCronJobs(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: WakeUpName, Namespace: "default"},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 8 * * *"},
+		},
+	)
+
+	err := CreateWakeUp(ctx, clientset, WakeUp{Namespace: "default", At: time.Now(), Image: "szero"}, false)
+	assert.Error(t, err)
+	cronJob, err := clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "0 8 * * *", cronJob.Spec.Schedule)
+	at, err := GetWakeUpDeadline(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Nil(t, at)
+
+	cancelled, err := DeleteWakeUp(ctx, clientset, "default", false)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+	_, err = clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestDeleteWakeUp(t *testing.T) {
+	ctx := context.Background()
+	clientset := testclient.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+
+	cancelled, err := DeleteWakeUp(ctx, clientset, "default", false)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+
+	assert.NoError(t, CreateWakeUp(ctx, clientset, WakeUp{Namespace: "default", At: time.Now(), Image: "szero"}, false))
+
+	cancelled, err = DeleteWakeUp(ctx, clientset, "default", true)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+	_, err = clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	cancelled, err = DeleteWakeUp(ctx, clientset, "default", false)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+	at, err := GetWakeUpDeadline(ctx, clientset, "default")
+	assert.NoError(t, err)
+	assert.Nil(t, at)
+	_, err = clientset.BatchV1().CronJobs("default").Get(ctx, WakeUpName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
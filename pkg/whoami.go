@@ -0,0 +1,19 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetCurrentUser returns the name the API server authenticates the client as, using a SelfSubjectReview
+func GetCurrentUser(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error reviewing the current user: %w", err)
+	}
+	return review.Status.UserInfo.Username, nil
+}
@@ -8,6 +8,7 @@ source ./tests/testing.sh
 kubectl apply -f ./tests/deployment.yaml
 kubectl apply -f ./tests/statefulset.yaml
 kubectl apply -f ./tests/daemonset.yaml
+kubectl apply -f ./tests/cronjob.yaml
 
 echo "Waiting for resources to be ready..."
 
@@ -19,6 +20,7 @@ assert "kubectl get pods --no-headers | wc -l" "6"
 assert "kubectl get deployment testdeployment001 -o jsonpath='{.spec.replicas}'" "3"
 assert "kubectl get sts teststatefulset001 -o jsonpath='{.spec.replicas}'" "2"
 assert "kubectl get ds testdaemonset001 -o jsonpath='{.status.desiredNumberScheduled}'" "1"
+assert "kubectl get cronjob testcronjob001 -o jsonpath='{.spec.suspend}'" "false"
 
 echo "Test dry-run mode (should not change anything)"
 
@@ -27,6 +29,7 @@ echo "Test dry-run mode (should not change anything)"
 assert "kubectl get deployment testdeployment001 -o jsonpath='{.spec.replicas}'" "3"
 assert "kubectl get sts teststatefulset001 -o jsonpath='{.spec.replicas}'" "2"
 assert "kubectl get ds testdaemonset001 -o jsonpath='{.status.desiredNumberScheduled}'" "1"
+assert "kubectl get cronjob testcronjob001 -o jsonpath='{.spec.suspend}'" "false"
 assert "kubectl get pods --no-headers | wc -l" "6"
 
 echo "Test actual downscale"
@@ -35,6 +38,7 @@ echo "Test actual downscale"
 assert "kubectl get deployment testdeployment001 -o jsonpath='{.spec.replicas}'" "0"
 assert "kubectl get sts teststatefulset001 -o jsonpath='{.spec.replicas}'" "0"
 assert "kubectl get ds testdaemonset001 -o jsonpath='{.status.desiredNumberScheduled}'" "0"
+assert "kubectl get cronjob testcronjob001 -o jsonpath='{.spec.suspend}'" "true"
 assert_eventually "kubectl get pods --no-headers | wc -l" "0"
 
 echo "Test dry-run mode for upscale (should not change anything)"
@@ -43,6 +47,7 @@ echo "Test dry-run mode for upscale (should not change anything)"
 assert "kubectl get deployment testdeployment001 -o jsonpath='{.spec.replicas}'" "0"
 assert "kubectl get sts teststatefulset001 -o jsonpath='{.spec.replicas}'" "0"
 assert "kubectl get ds testdaemonset001 -o jsonpath='{.status.desiredNumberScheduled}'" "0"
+assert "kubectl get cronjob testcronjob001 -o jsonpath='{.spec.suspend}'" "true"
 assert "kubectl get pods --no-headers | wc -l" "0"
 
 echo "Test actual upscale"
@@ -52,3 +57,4 @@ assert "kubectl get deployment testdeployment001 -o jsonpath='{.spec.replicas}'"
 assert "kubectl get sts teststatefulset001 -o jsonpath='{.spec.replicas}'" "2"
 assert_eventually "kubectl get pods --no-headers | wc -l" "6"
 assert "kubectl get ds testdaemonset001 -o jsonpath='{.status.desiredNumberScheduled}'" "1"
+assert "kubectl get cronjob testcronjob001 -o jsonpath='{.spec.suspend}'" "false"